@@ -1,20 +1,24 @@
 package aidbox
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
-	"log"
 )
 
 type AccessPolicy struct {
 	ResourceBase
 	Description string             `json:"description,omitempty"`
+	RoleName    string             `json:"roleName,omitempty"`
+	Type        string             `json:"type,omitempty"`
 	Engine      AccessPolicyEngine `json:"engine"`
 	Schema      json.RawMessage    `json:"schema,omitempty"`
 	Link        []Reference        `json:"link,omitempty"`
 	Matcho      json.RawMessage    `json:"matcho,omitempty"`
 	Rpc         json.RawMessage    `json:"rpc,omitempty"`
+	Sql         *AccessPolicySql   `json:"sql,omitempty"`
+	Clj         string             `json:"clj,omitempty"`
+	And         []AccessPolicyRule `json:"and,omitempty"`
+	Or          []AccessPolicyRule `json:"or,omitempty"`
 }
 
 func (*AccessPolicy) GetResourcePath() string {
@@ -26,80 +30,53 @@ type Reference struct {
 	ResourceType string `json:"resourceType"`
 }
 
-type AccessPolicyEngine int
+// AccessPolicySql is the check of the sql engine, a query which grants access if it returns true
+type AccessPolicySql struct {
+	Query string `json:"query"`
+}
+
+// AccessPolicyRule is a policy nested in the and/or of a complex policy
+type AccessPolicyRule struct {
+	Engine AccessPolicyEngine `json:"engine"`
+	Schema json.RawMessage    `json:"schema,omitempty"`
+	Matcho json.RawMessage    `json:"matcho,omitempty"`
+	Sql    *AccessPolicySql   `json:"sql,omitempty"`
+	Clj    string             `json:"clj,omitempty"`
+	And    []AccessPolicyRule `json:"and,omitempty"`
+	Or     []AccessPolicyRule `json:"or,omitempty"`
+}
+
+type AccessPolicyEngine string
 
 const (
-	AccessPolicyEngineJsonSchema AccessPolicyEngine = iota
-	AccessPolicyEngineAllow
-	//AccessPolicyEngineSql
-	//AccessPolicyEngineComplex
-	AccessPolicyEngineMatcho
-	//AccessPolicyEngineClj
-	AccessPolicyEngineMatchoRpc
+	AccessPolicyEngineJsonSchema AccessPolicyEngine = "json-schema"
+	AccessPolicyEngineAllow      AccessPolicyEngine = "allow"
+	AccessPolicyEngineSql        AccessPolicyEngine = "sql"
+	AccessPolicyEngineComplex    AccessPolicyEngine = "complex"
+	AccessPolicyEngineMatcho     AccessPolicyEngine = "matcho"
+	AccessPolicyEngineClj        AccessPolicyEngine = "clj"
+	AccessPolicyEngineMatchoRpc  AccessPolicyEngine = "matcho-rpc"
 )
 
-func (g AccessPolicyEngine) ToString() string {
-	switch g {
-	case AccessPolicyEngineJsonSchema:
-		return "json-schema"
-	case AccessPolicyEngineAllow:
-		return "allow"
-		//case AccessPolicyEngineSql:
-		//	return "sql"
-		//case AccessPolicyEngineComplex:
-		//	return "complex"
-	case AccessPolicyEngineMatcho:
-		return "matcho"
-		//case AccessPolicyEngineClj:
-		//	return "clj"
-	case AccessPolicyEngineMatchoRpc:
-		return "matcho-rpc"
-	}
-	log.Panicf("Unexpected AccessPolicyEngine %d\n", g)
-	return ""
+// AccessPolicyEngines are the engines the provider supports
+var AccessPolicyEngines = EnumValues[AccessPolicyEngine]{
+	AccessPolicyEngineJsonSchema,
+	AccessPolicyEngineAllow,
+	AccessPolicyEngineSql,
+	AccessPolicyEngineComplex,
+	AccessPolicyEngineMatcho,
+	AccessPolicyEngineClj,
+	AccessPolicyEngineMatchoRpc,
 }
 
 const ErrInvalidAccessPolicyEngine AidboxError = "Invalid access policy engine type"
 
 func ParseAccessPolicyEngine(s string) (AccessPolicyEngine, error) {
-	switch s {
-	case "json-schema":
-		return AccessPolicyEngineJsonSchema, nil
-	case "allow":
-		return AccessPolicyEngineAllow, nil
-	//case "sql":
-	//	return AccessPolicyEngineSql, nil
-	//case "complex":
-	//	return AccessPolicyEngineComplex, nil
-	case "matcho":
-		return AccessPolicyEngineMatcho, nil
-	case "matcho-rpc":
-		return AccessPolicyEngineMatchoRpc, nil
-	//case "clj":
-	//	return AccessPolicyEngineClj, nil
-	default:
-		return 0, ErrInvalidAccessPolicyEngine
-	}
+	return AccessPolicyEngines.parse(s, ErrInvalidAccessPolicyEngine)
 }
 
-func (g AccessPolicyEngine) MarshalJSON() ([]byte, error) {
-	buffer := bytes.NewBufferString(`"`)
-	buffer.WriteString(g.ToString())
-	buffer.WriteString(`"`)
-	return buffer.Bytes(), nil
-}
-
-func (g *AccessPolicyEngine) UnmarshalJSON(b []byte) error {
-	var j string
-	if err := json.Unmarshal(b, &j); err != nil {
-		return err
-	}
-	tt, err := ParseAccessPolicyEngine(j)
-	if err != nil {
-		return err
-	}
-	*g = tt
-	return nil
+func (e AccessPolicyEngine) IsKnown() bool {
+	return AccessPolicyEngines.Contains(e)
 }
 
 func (apiClient *ApiClient) CreateAccessPolicy(ctx context.Context, accessPolicy *AccessPolicy) (*AccessPolicy, error) {
@@ -112,6 +89,10 @@ func (apiClient *ApiClient) GetAccessPolicy(ctx context.Context, id string) (*Ac
 	return response, apiClient.getResource(ctx, id, response)
 }
 
+func (apiClient *ApiClient) ListAccessPolicies(ctx context.Context) ([]*AccessPolicy, error) {
+	return listResources[AccessPolicy](ctx, apiClient, (&AccessPolicy{}).GetResourcePath())
+}
+
 func (apiClient *ApiClient) UpdateAccessPolicy(ctx context.Context, q *AccessPolicy) (*AccessPolicy, error) {
 	response := &AccessPolicy{}
 	return response, apiClient.updateResource(ctx, q, response)
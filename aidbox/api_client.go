@@ -4,32 +4,66 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path"
+	"sync"
+	"time"
 )
 
 type ApiClient struct {
 	URL      string
 	Username string
 	Password string
+	// AuthMethod selects how requests are authenticated, defaults to AuthMethodBasic
+	AuthMethod AuthMethod
+	// MaxRetries is how many times a request failing with a transient error is retried, the zero value disables
+	// retries. The provider defaults to 3.
+	MaxRetries int
+	// RetryMinWait and RetryMaxWait bound the exponential backoff between retries
+	RetryMinWait time.Duration
+	RetryMaxWait time.Duration
+	// HTTPClient sends the requests, replace it with one from NewHTTPClient to customise timeouts or TLS
+	HTTPClient *http.Client
+	// OptimisticLocking makes updates fail with ResourceChangedError if the resource was changed since it was read,
+	// enabled by default
+	OptimisticLocking bool
+	// MaxConcurrentRequests and RequestsPerSecond limit the load put on aidbox, unlimited if zero. They're read when
+	// the first request is sent.
+	MaxConcurrentRequests int
+	RequestsPerSecond     float64
+
+	tokenLock    sync.Mutex
+	token        *accessToken
+	throttleOnce sync.Once
+	throttle     *throttle
 }
 
 type AidboxError string
 
 const NotFoundError AidboxError = "Not found"
 
+const ResourceChangedError AidboxError = "resource changed since last refresh"
+
 func (t AidboxError) Error() string {
 	return string(t)
 }
 
 func NewApiClient(URL, username, password string) *ApiClient {
 	return &ApiClient{
-		URL:      URL,
-		Username: username,
-		Password: password,
+		URL:          URL,
+		Username:     username,
+		Password:     password,
+		AuthMethod:   AuthMethodBasic,
+		RetryMinWait: time.Second,
+		RetryMaxWait: 30 * time.Second,
+		HTTPClient: &http.Client{
+			Transport: http.DefaultTransport.(*http.Transport).Clone(),
+		},
+		OptimisticLocking: true,
 	}
 }
 
@@ -46,47 +80,95 @@ func (apiClient *ApiClient) getResource(ctx context.Context, id string, response
 }
 
 func (apiClient *ApiClient) updateResource(ctx context.Context, resource Resource, responseTarget any) error {
-	return apiClient.put(ctx, resource, path.Join("/", resource.GetResourcePath(), "/", resource.GetID()), responseTarget)
+	return apiClient.putVersion(ctx, resource, path.Join("/", resource.GetResourcePath(), "/", resource.GetID()), resource.GetVersionId(), responseTarget)
 }
 
 func (apiClient *ApiClient) deleteResource(ctx context.Context, id string, responseTarget Resource) error {
 	return apiClient.send(ctx, struct{}{}, path.Join("/", responseTarget.GetResourcePath(), id), &struct{}{}, http.MethodDelete)
 }
 
+// listResources reads every resource from the list endpoint of a type, page by page
+func listResources[T any](ctx context.Context, apiClient *ApiClient, resourcePath string) ([]*T, error) {
+	entries, err := apiClient.searchAll(ctx, path.Join("/", resourcePath))
+	if err != nil {
+		return nil, err
+	}
+	resources := make([]*T, 0, len(entries))
+	for _, entry := range entries {
+		resource := new(T)
+		err = json.Unmarshal(entry, resource)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// yamlBody is a request body sent as it is with the YAML content type, rather than encoded to JSON
+type yamlBody string
+
 func (apiClient *ApiClient) put(ctx context.Context, requestBody interface{}, relativePath string, responseT interface{}) error {
 	return apiClient.send(ctx, requestBody, relativePath, responseT, http.MethodPut)
 }
 
+// putVersion updates the resource only if it's still at the given version, unless optimistic locking is disabled or
+// the version isn't known
+func (apiClient *ApiClient) putVersion(ctx context.Context, requestBody interface{}, relativePath string, versionId string, responseT interface{}) error {
+	header := http.Header{}
+	if apiClient.OptimisticLocking && versionId != "" {
+		header.Set("If-Match", fmt.Sprintf(`W/"%s"`, versionId))
+	}
+	err := apiClient.sendWithHeader(ctx, requestBody, relativePath, responseT, http.MethodPut, header)
+	if errors.Is(err, ResourceChangedError) {
+		return fmt.Errorf("%s %w (expected version %s), refresh and review the plan before applying again", relativePath, ResourceChangedError, versionId)
+	}
+	return err
+}
+
 func (apiClient *ApiClient) post(ctx context.Context, requestBody interface{}, relativePath string, responseT interface{}) error {
 	return apiClient.send(ctx, requestBody, relativePath, responseT, http.MethodPost)
 }
 
 func (apiClient *ApiClient) send(ctx context.Context, requestBody interface{}, relativePath string, responseT interface{}, httpMethod string) error {
+	return apiClient.sendWithHeader(ctx, requestBody, relativePath, responseT, httpMethod, nil)
+}
+
+func (apiClient *ApiClient) sendWithHeader(ctx context.Context, requestBody interface{}, relativePath string, responseT interface{}, httpMethod string, header http.Header) error {
 	buf := bytes.Buffer{}
-	err := json.NewEncoder(&buf).Encode(requestBody)
-	if err != nil {
-		return err
+	contentType := "application/json"
+	if yaml, ok := requestBody.(yamlBody); ok {
+		buf.WriteString(string(yaml))
+		contentType = "text/yaml"
+	} else {
+		err := json.NewEncoder(&buf).Encode(requestBody)
+		if err != nil {
+			return err
+		}
 	}
 	req, err := http.NewRequestWithContext(ctx, httpMethod, apiClient.URL+relativePath, &buf)
 	if err != nil {
 		return err
 	}
 
-	apiClient.addAuthAndHost(req)
-	req.Header.Set("Content-Type", "application/json")
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
+	for k, v := range header {
+		req.Header[k] = v
 	}
-	body, err := io.ReadAll(res.Body)
+	req.Header.Set("Content-Type", contentType)
+	if req.Header.Get("Accept") == "" {
+		// aidbox would otherwise respond in the format of the request
+		req.Header.Set("Accept", "application/json")
+	}
+	res, body, err := apiClient.do(req)
 	if err != nil {
 		return err
 	}
 	if !isAlright(res.StatusCode) {
 		return errorToTerraform(req, res, requestBody, body)
 	}
-	// Deletes in general return the resource you deleted in the response body, but sometimes not (e.g. SearchParameter)
-	if httpMethod == http.MethodDelete && len(body) == 0 {
+	// Deletes in general return the resource you deleted in the response body, but sometimes not (e.g. SearchParameter),
+	// and operations may respond with no content
+	if len(body) == 0 && (httpMethod == http.MethodDelete || res.StatusCode == http.StatusNoContent || res.StatusCode == http.StatusAccepted) {
 		return nil
 	}
 
@@ -98,13 +180,8 @@ func (apiClient *ApiClient) get(ctx context.Context, relativePath string, respon
 	if err != nil {
 		return err
 	}
-	apiClient.addAuthAndHost(req)
 	req.Header.Set("Accept", "application/json")
-	res, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	body, err := io.ReadAll(res.Body)
+	res, body, err := apiClient.do(req)
 	if err != nil {
 		return err
 	}
@@ -119,28 +196,92 @@ func (apiClient *ApiClient) get(ctx context.Context, relativePath string, respon
 	return json.Unmarshal(body, responseT)
 }
 
-func (apiClient *ApiClient) addAuthAndHost(req *http.Request) {
-	req.SetBasicAuth(apiClient.Username, apiClient.Password)
+// doAuthenticated authenticates and executes the request, returning the response with its body already read. A request
+// rejected with 401 while using a bearer token is sent again once with a freshly issued token, in case the cached one
+// was revoked.
+func (apiClient *ApiClient) doAuthenticated(req *http.Request) (*http.Response, []byte, error) {
+	res, body, err := apiClient.doOnce(req)
+	if err != nil || res.StatusCode != http.StatusUnauthorized || apiClient.AuthMethod != AuthMethodClientCredentials {
+		return res, body, err
+	}
+	apiClient.invalidateAccessToken()
+	retry, err := cloneRequest(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apiClient.doOnce(retry)
+}
+
+func (apiClient *ApiClient) doOnce(req *http.Request) (*http.Response, []byte, error) {
+	err := apiClient.addAuthAndHost(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return apiClient.doThrottled(req)
+}
+
+// doThrottled executes the request as it is once it's its turn, returning the response with its body already read
+func (apiClient *ApiClient) doThrottled(req *http.Request) (*http.Response, []byte, error) {
+	release, err := apiClient.waitForTurn(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer release()
+	res, err := apiClient.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, body, nil
+}
+
+// cloneRequest copies the request including a fresh reader for its body, so that it can be sent again
+func cloneRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		clone.Body = body
+	}
+	return clone, nil
 }
 
-// errorToTerraform pretty prints the response body. Very often you get a 422 with useful details in the response body
-// only. Print this into an error so terraform can show it to the user. Request details are also printed during testing.
+func (apiClient *ApiClient) addAuthAndHost(req *http.Request) error {
+	if apiClient.AuthMethod != AuthMethodClientCredentials {
+		req.SetBasicAuth(apiClient.Username, apiClient.Password)
+		return nil
+	}
+	token, err := apiClient.getAccessToken(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// errorToTerraform wraps a non-2xx response into an APIError. Very often you get a 422 with useful details in the
+// response body only, which are parsed if it's an OperationOutcome, and printed into the error message so terraform can
+// show it to the user. Request details are also printed during testing.
 func errorToTerraform(request *http.Request, response *http.Response, requestBody interface{}, responseBody []byte) error {
-	var sensitiveDetails = ""
-	var prettyResponse bytes.Buffer
-	jsonParseErr := json.Indent(&prettyResponse, responseBody, "", "  ")
-	if jsonParseErr != nil {
-		return fmt.Errorf("unexpected status code (%d) received: %s\n\n"+
-			"===== %s %s =====\n\n"+
-			"===== RESPONSE BODY =====\n"+
-			"%s\n",
-			response.StatusCode,
-			response.Status,
-			request.Method, request.URL.String(),
-			string(responseBody))
-	}
-
-	if os.Getenv("TF_ACC") == "1" {
+	apiError := &APIError{
+		StatusCode: response.StatusCode,
+		Status:     response.Status,
+		Method:     request.Method,
+		URL:        request.URL.String(),
+		Body:       responseBody,
+	}
+	outcome := OperationOutcome{}
+	if json.Unmarshal(responseBody, &outcome) == nil && outcome.ResourceType == "OperationOutcome" {
+		apiError.Issues = outcome.Issue
+	}
+
+	if os.Getenv("TF_ACC") == "1" && json.Valid(responseBody) {
 		prettyRequest, err := json.MarshalIndent(requestBody, "", "  ")
 		if err != nil {
 			panic(err)
@@ -149,21 +290,12 @@ func errorToTerraform(request *http.Request, response *http.Response, requestBod
 		if err != nil {
 			panic(err)
 		}
-		sensitiveDetails = fmt.Sprintf("\n\n===== REQUEST HEADERS =====\n"+
+		apiError.requestDetails = fmt.Sprintf("\n\n===== REQUEST HEADERS =====\n"+
 			"%s\n\n"+
 			"===== REQUEST BODY =====\n"+
 			"%s",
 			prettyHeaders,
 			prettyRequest)
 	}
-
-	return fmt.Errorf("unexpected status code (%d) received: %s\n\n"+
-		"===== %s %s =====%s\n\n"+
-		"===== RESPONSE BODY =====\n"+
-		"%s\n",
-		response.StatusCode,
-		response.Status,
-		request.Method, request.URL.String(),
-		sensitiveDetails,
-		prettyResponse.String())
+	return apiError
 }
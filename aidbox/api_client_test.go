@@ -2,11 +2,19 @@ package aidbox
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -100,3 +108,684 @@ Service Unavailable
 		assert.Equal(t, expectedError, err.Error())
 	})
 }
+
+func TestApiClientErrors(t *testing.T) {
+	t.Run("should parse the issues of an OperationOutcome", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(422)
+			w.Write([]byte(`{"resourceType": "OperationOutcome", "issue": [
+				{"severity": "fatal", "code": "invalid", "expression": ["AccessPolicy.engine"], "diagnostics": "Unknown engine"},
+				{"severity": "warning", "code": "informational", "diagnostics": "Deprecated"}
+			]}`))
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		err := client.put(context.TODO(), "", "/AccessPolicy/ap", "")
+
+		var apiError *APIError
+		assert.True(t, errors.As(err, &apiError))
+		assert.Equal(t, 422, apiError.StatusCode)
+		assert.Equal(t, http.MethodPut, apiError.Method)
+		assert.Equal(t, server.URL+"/AccessPolicy/ap", apiError.URL)
+		assert.Equal(t, []OperationOutcomeIssue{
+			{Severity: "fatal", Code: "invalid", Expression: []string{"AccessPolicy.engine"}, Diagnostics: "Unknown engine"},
+			{Severity: "warning", Code: "informational", Diagnostics: "Deprecated"},
+		}, apiError.Issues)
+	})
+
+	t.Run("should not find issues in other responses", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(500)
+			w.Write([]byte(`{"issue": "Octarine"}`))
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		err := client.post(context.TODO(), "", "/endpoint", "")
+
+		var apiError *APIError
+		assert.True(t, errors.As(err, &apiError))
+		assert.Empty(t, apiError.Issues)
+	})
+
+	t.Run("should report any 404 as not found", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(404)
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		assert.ErrorIs(t, client.get(context.TODO(), "/endpoint", &TestResponse{}), NotFoundError)
+		assert.ErrorIs(t, client.send(context.TODO(), struct{}{}, "/endpoint", &struct{}{}, http.MethodDelete), NotFoundError)
+	})
+}
+
+func TestApiClientClientCredentials(t *testing.T) {
+	newTokenServer := func(expiresIn int, tokensIssued *int, revoked map[string]bool) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/auth/token" {
+				request := tokenRequest{}
+				json.NewDecoder(r.Body).Decode(&request)
+				if request.GrantType != "client_credentials" || request.ClientId != "foo" || request.ClientSecret != "bar" {
+					w.WriteHeader(401)
+					return
+				}
+				*tokensIssued++
+				w.WriteHeader(200)
+				fmt.Fprintf(w, "{\"access_token\": \"token-%d\", \"token_type\": \"Bearer\", \"expires_in\": %d}", *tokensIssued, expiresIn)
+				return
+			}
+			authorization := r.Header.Get("Authorization")
+			if !strings.HasPrefix(authorization, "Bearer token-") || revoked[authorization] {
+				w.WriteHeader(401)
+				return
+			}
+			w.WriteHeader(200)
+			fmt.Fprintf(w, "{\"name\": \"%s\", \"value\": %d}", authorization, *tokensIssued)
+		}))
+	}
+
+	t.Run("should use a cached bearer token instead of basic auth", func(t *testing.T) {
+		tokensIssued := 0
+		server := newTokenServer(3600, &tokensIssued, nil)
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		client.AuthMethod = AuthMethodClientCredentials
+		for i := 0; i < 3; i++ {
+			response := &TestResponse{}
+			err := client.post(context.TODO(), "", "/endpoint", response)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, TestResponse{"Bearer token-1", 1}, *response)
+		}
+	})
+
+	t.Run("should refresh the token before it expires", func(t *testing.T) {
+		tokensIssued := 0
+		// a token living for 1s is due for refresh after half of its lifetime
+		server := newTokenServer(1, &tokensIssued, nil)
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		client.AuthMethod = AuthMethodClientCredentials
+		response := &TestResponse{}
+		assert.Equal(t, nil, client.get(context.TODO(), "/endpoint", response))
+		time.Sleep(600 * time.Millisecond)
+		assert.Equal(t, nil, client.get(context.TODO(), "/endpoint", response))
+		assert.Equal(t, TestResponse{"Bearer token-2", 2}, *response)
+	})
+
+	t.Run("should request a new token once if the cached one is rejected", func(t *testing.T) {
+		tokensIssued := 0
+		revoked := map[string]bool{}
+		server := newTokenServer(3600, &tokensIssued, revoked)
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		client.AuthMethod = AuthMethodClientCredentials
+		response := &TestResponse{}
+		assert.Equal(t, nil, client.post(context.TODO(), "", "/endpoint", response))
+		revoked["Bearer token-1"] = true
+		assert.Equal(t, nil, client.post(context.TODO(), "", "/endpoint", response))
+		assert.Equal(t, TestResponse{"Bearer token-2", 2}, *response)
+	})
+
+	t.Run("should fail if the token can't be obtained", func(t *testing.T) {
+		tokensIssued := 0
+		server := newTokenServer(3600, &tokensIssued, nil)
+
+		client := NewApiClient(server.URL, "foo", "wrong")
+		client.AuthMethod = AuthMethodClientCredentials
+		err := client.post(context.TODO(), "", "/endpoint", &TestResponse{})
+
+		assert.ErrorContains(t, err, fmt.Sprintf("unexpected status code (401) received: 401 Unauthorized\n\n===== POST %s/auth/token =====", server.URL))
+		assert.Equal(t, 0, tokensIssued)
+	})
+
+	t.Run("should retry the token request while aidbox is restarting", func(t *testing.T) {
+		var tokenCalls, inFlight, maxInFlight atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/auth/token" {
+				current := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				if current > maxInFlight.Load() {
+					maxInFlight.Store(current)
+				}
+				if tokenCalls.Add(1) <= 2 {
+					w.WriteHeader(503)
+					return
+				}
+				w.Write([]byte(`{"access_token": "token-1", "token_type": "Bearer", "expires_in": 3600}`))
+				return
+			}
+			fmt.Fprintf(w, "{\"name\": \"%s\", \"value\": 1}", r.Header.Get("Authorization"))
+		}))
+		defer server.Close()
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		client.AuthMethod = AuthMethodClientCredentials
+		client.MaxRetries = 3
+		client.RetryMinWait = time.Millisecond
+		client.RetryMaxWait = 10 * time.Millisecond
+		client.MaxConcurrentRequests = 1
+		response := &TestResponse{}
+		err := client.post(context.TODO(), "", "/endpoint", response)
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, TestResponse{"Bearer token-1", 1}, *response)
+		assert.Equal(t, int32(3), tokenCalls.Load())
+		assert.Equal(t, int32(1), maxInFlight.Load())
+	})
+}
+
+func TestApiClientRetries(t *testing.T) {
+	newFlakyServer := func(failures int, status int, retryAfter string, calls *int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls++
+			if *calls <= failures {
+				if retryAfter != "" {
+					w.Header().Set("Retry-After", retryAfter)
+				}
+				w.WriteHeader(status)
+				return
+			}
+			// echo the request body back to check it's sent again on retries
+			body, _ := io.ReadAll(r.Body)
+			if len(body) == 0 {
+				body = []byte("\"\"")
+			}
+			w.WriteHeader(200)
+			fmt.Fprintf(w, "{\"name\": %s, \"value\": %d}", body, *calls)
+		}))
+	}
+	newRetryingClient := func(url string) *ApiClient {
+		client := NewApiClient(url, "foo", "bar")
+		client.MaxRetries = 3
+		client.RetryMinWait = time.Millisecond
+		client.RetryMaxWait = 10 * time.Millisecond
+		return client
+	}
+
+	t.Run("should retry idempotent requests on transient failures", func(t *testing.T) {
+		calls := 0
+		server := newFlakyServer(2, 503, "", &calls)
+
+		response := &TestResponse{}
+		err := newRetryingClient(server.URL).put(context.TODO(), "Twoflower", "/endpoint", response)
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, TestResponse{"Twoflower", 3}, *response)
+	})
+
+	t.Run("should give up after the maximum number of retries", func(t *testing.T) {
+		calls := 0
+		server := newFlakyServer(10, 504, "", &calls)
+
+		err := newRetryingClient(server.URL).get(context.TODO(), "/endpoint", &TestResponse{})
+
+		assert.ErrorContains(t, err, "unexpected status code (504)")
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("should not retry non-idempotent requests", func(t *testing.T) {
+		calls := 0
+		server := newFlakyServer(1, 503, "", &calls)
+
+		err := newRetryingClient(server.URL).post(context.TODO(), "", "/endpoint", &TestResponse{})
+
+		assert.ErrorContains(t, err, "unexpected status code (503)")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should not retry errors that aren't transient", func(t *testing.T) {
+		calls := 0
+		server := newFlakyServer(1, 500, "", &calls)
+
+		err := newRetryingClient(server.URL).get(context.TODO(), "/endpoint", &TestResponse{})
+
+		assert.ErrorContains(t, err, "unexpected status code (500)")
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should wait as long as Retry-After asks, up to the maximum wait", func(t *testing.T) {
+		calls := 0
+		server := newFlakyServer(1, 429, "1", &calls)
+		client := newRetryingClient(server.URL)
+		client.RetryMaxWait = 200 * time.Millisecond
+
+		start := time.Now()
+		err := client.get(context.TODO(), "/endpoint", &TestResponse{})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 2, calls)
+		assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	wait, ok := parseRetryAfter("120", now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, wait)
+
+	wait, ok = parseRetryAfter("Mon, 01 Jan 2024 12:00:30 GMT", now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, wait)
+
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
+
+func TestValidateResource(t *testing.T) {
+	t.Run("should return the issues of an invalid resource", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/fhir/Patient/$validate", r.URL.Path)
+			w.WriteHeader(422)
+			w.Write([]byte(`{"resourceType": "OperationOutcome", "issue": [{"severity": "error", "code": "invalid", "expression": ["Patient.gender"]}]}`))
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		outcome, err := client.ValidateResource(context.TODO(), []byte(`{"resourceType": "Patient", "gender": "troll"}`))
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []OperationOutcomeIssue{{Severity: "error", Code: "invalid", Expression: []string{"Patient.gender"}}}, outcome.Issue)
+	})
+
+	t.Run("should fail if the resource couldn't be validated", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(503)
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		_, err := client.ValidateResource(context.TODO(), []byte(`{"resourceType": "Patient"}`))
+
+		assert.ErrorContains(t, err, "unexpected status code (503)")
+	})
+}
+
+func TestSearchResources(t *testing.T) {
+	t.Run("should follow the next links until the last page", func(t *testing.T) {
+		var requests []string
+		var server *httptest.Server
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r.URL.RequestURI())
+			w.WriteHeader(200)
+			switch r.URL.Query().Get("page") {
+			case "":
+				fmt.Fprintf(w, `{"entry": [{"resource": {"id": "1"}}, {"resource": {"id": "2"}}],
+					"link": [{"relation": "first", "url": "%[1]s/fhir/Organization?name=guild&page=1"}, {"relation": "next", "url": "%[1]s/fhir/Organization?name=guild&page=2"}]}`, server.URL)
+			case "2":
+				// as seen from behind a reverse proxy
+				w.Write([]byte(`{"entry": [{"resource": {"id": "3"}}],
+					"link": [{"relation": "next", "url": "https://aidbox.example.com/fhir/Organization?name=guild&page=3"}]}`))
+			case "3":
+				w.Write([]byte(`{"entry": [], "link": [{"relation": "self", "url": "/fhir/Organization?name=guild&page=3"}]}`))
+			}
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		resources, err := client.SearchResources(context.TODO(), "Organization", url.Values{"name": {"guild"}})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []json.RawMessage{[]byte(`{"id": "1"}`), []byte(`{"id": "2"}`), []byte(`{"id": "3"}`)}, resources)
+		assert.Equal(t, []string{
+			"/fhir/Organization?name=guild",
+			"/fhir/Organization?name=guild&page=2",
+			"/fhir/Organization?name=guild&page=3",
+		}, requests)
+	})
+
+	t.Run("should stop if a page links to itself", func(t *testing.T) {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(200)
+			w.Write([]byte(`{"entry": [{"resource": {"id": "1"}}], "link": [{"relation": "next", "url": "/fhir/Organization"}]}`))
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		resources, err := client.SearchResources(context.TODO(), "Organization", nil)
+
+		assert.Equal(t, nil, err)
+		assert.Len(t, resources, 1)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestSubmitTransaction(t *testing.T) {
+	t.Run("should post a transaction bundle", func(t *testing.T) {
+		var submitted map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "POST", r.Method)
+			assert.Equal(t, "/", r.URL.Path)
+			json.NewDecoder(r.Body).Decode(&submitted)
+			w.WriteHeader(200)
+			w.Write([]byte(`{"resourceType": "Bundle", "type": "transaction-response", "entry": [
+				{"resource": {"resourceType": "ValueSet", "id": "colours"}, "response": {"status": "200"}},
+				{"response": {"status": "201", "location": "Questionnaire/generated/_history/1"}},
+				{"response": {"status": "204"}}]}`))
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		response, err := client.SubmitTransaction(context.TODO(), []BundleEntry{
+			{Resource: []byte(`{"resourceType": "ValueSet", "id": "colours"}`), Request: &BundleEntryRequest{Method: "PUT", Url: "ValueSet/colours"}},
+			{Resource: []byte(`{"resourceType": "Questionnaire"}`), Request: &BundleEntryRequest{Method: "POST", Url: "Questionnaire"}},
+			{Request: &BundleEntryRequest{Method: "DELETE", Url: "AccessPolicy/old"}},
+		})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "Bundle", submitted["resourceType"])
+		assert.Equal(t, "transaction", submitted["type"])
+		assert.Equal(t, map[string]any{"request": map[string]any{"method": "DELETE", "url": "AccessPolicy/old"}}, submitted["entry"].([]any)[2])
+		assert.Len(t, response.Entry, 3)
+	})
+
+	t.Run("should fail if the response doesn't match the submitted entries", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(200)
+			w.Write([]byte(`{"resourceType": "Bundle", "type": "transaction-response", "entry": []}`))
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		_, err := client.SubmitTransaction(context.TODO(), []BundleEntry{{Request: &BundleEntryRequest{Method: "DELETE", Url: "AccessPolicy/old"}}})
+
+		assert.ErrorContains(t, err, "expected 1 entries in the transaction response but got 0")
+	})
+}
+
+func TestConditionalCreateGenericResource(t *testing.T) {
+	t.Run("should send the conditional create query as If-None-Exist", func(t *testing.T) {
+		var ifNoneExist, requestURI, method string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ifNoneExist = r.Header.Get("If-None-Exist")
+			requestURI = r.URL.RequestURI()
+			method = r.Method
+			w.WriteHeader(200)
+			w.Write([]byte(`{"resourceType": "Organization", "id": "existing"}`))
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		res, err := client.CreateGenericResource(context.TODO(), &GenericResource{
+			ResourceContent:        []byte(`{"resourceType": "Organization"}`),
+			ConditionalCreateQuery: "identifier=http://example.com/ods|ABC",
+		})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "POST", method)
+		assert.Equal(t, "/Organization", requestURI)
+		assert.Equal(t, "identifier=http://example.com/ods|ABC", ifNoneExist)
+		assert.Equal(t, "Organization/existing", res.ResourceTypeAndId)
+	})
+
+	t.Run("should put to the conditional update query", func(t *testing.T) {
+		var requestURI, method string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestURI = r.URL.RequestURI()
+			method = r.Method
+			w.WriteHeader(201)
+			w.Write([]byte(`{"resourceType": "Organization", "id": "created"}`))
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		res, err := client.CreateGenericResource(context.TODO(), &GenericResource{
+			ResourceContent:        []byte(`{"resourceType": "Organization"}`),
+			ConditionalUpdateQuery: "identifier=ABC",
+		})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "PUT", method)
+		assert.Equal(t, "/Organization?identifier=ABC", requestURI)
+		assert.Equal(t, "Organization/created", res.ResourceTypeAndId)
+	})
+
+	t.Run("should explain that the query matched more than one resource", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(412)
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		_, err := client.CreateGenericResource(context.TODO(), &GenericResource{
+			ResourceContent:        []byte(`{"resourceType": "Organization"}`),
+			ConditionalUpdateQuery: "name=guild",
+		})
+
+		assert.ErrorContains(t, err, "more than one Organization matches name=guild")
+	})
+}
+
+func TestYamlGenericResource(t *testing.T) {
+	t.Run("should send the YAML content as it is", func(t *testing.T) {
+		var contentType, accept, body string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			contentType = r.Header.Get("Content-Type")
+			accept = r.Header.Get("Accept")
+			b, _ := io.ReadAll(r.Body)
+			body = string(b)
+			w.WriteHeader(201)
+			w.Write([]byte(`{"resourceType": "Organization", "id": "guild"}`))
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		res, err := client.CreateGenericResource(context.TODO(), &GenericResource{
+			ResourceContent: []byte(`{"resourceType": "Organization", "id": "guild"}`),
+			Yaml:            "# the guild\nresourceType: Organization\nid: guild\n",
+		})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "text/yaml", contentType)
+		assert.Equal(t, "application/json", accept)
+		assert.Equal(t, "# the guild\nresourceType: Organization\nid: guild\n", body)
+		assert.Equal(t, "Organization/guild", res.ResourceTypeAndId)
+	})
+}
+
+func TestPurgeGenericResource(t *testing.T) {
+	t.Run("should post to $purge and accept an empty response", func(t *testing.T) {
+		var method, requestPath string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method = r.Method
+			requestPath = r.URL.Path
+			w.WriteHeader(204)
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		err := client.PurgeGenericResource(context.TODO(), "Patient/test")
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "POST", method)
+		assert.Equal(t, "/fhir/Patient/test/$purge", requestPath)
+	})
+
+	t.Run("should refuse types without $purge", func(t *testing.T) {
+		client := NewApiClient("http://localhost:1", "foo", "bar")
+		err := client.PurgeGenericResource(context.TODO(), "Observation/weight")
+
+		assert.ErrorIs(t, err, ErrNotPurgeable)
+	})
+}
+
+func TestFindReferencingResources(t *testing.T) {
+	t.Run("should list what's included by _revinclude but the resource itself", func(t *testing.T) {
+		var query url.Values
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query()
+			w.WriteHeader(200)
+			w.Write([]byte(`{"entry": [{"resource": {"resourceType": "Patient", "id": "test"}},
+				{"resource": {"resourceType": "Observation", "id": "weight"}},
+				{"resource": {"resourceType": "Encounter", "id": "visit"}}]}`))
+		}))
+
+		client := NewApiClient(server.URL, "foo", "bar")
+		referencing, err := client.FindReferencingResources(context.TODO(), "Patient/test")
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "test", query.Get("_id"))
+		assert.Equal(t, "*:*", query.Get("_revinclude"))
+		assert.Equal(t, []string{"Observation/weight", "Encounter/visit"}, referencing)
+	})
+}
+
+func TestOptimisticLocking(t *testing.T) {
+	var ifMatch string
+	newServer := func(currentVersion string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ifMatch = r.Header.Get("If-Match")
+			if ifMatch != "" && ifMatch != `W/"`+currentVersion+`"` {
+				w.WriteHeader(412)
+				w.Write([]byte(`{"resourceType": "OperationOutcome", "issue": [{"severity": "error", "code": "conflict"}]}`))
+				return
+			}
+			w.Write([]byte(`{"resourceType": "Organization", "id": "guild", "meta": {"versionId": "` + currentVersion + `"}}`))
+		}))
+	}
+
+	t.Run("should update the version it was given", func(t *testing.T) {
+		server := newServer("2")
+		defer server.Close()
+		client := NewApiClient(server.URL, "foo", "bar")
+
+		res, err := client.UpdateGenericResource(context.TODO(), &GenericResource{
+			ResourceTypeAndId: "Organization/guild",
+			ResourceContent:   []byte(`{"resourceType": "Organization", "id": "guild"}`),
+			Meta:              &ResourceBaseMeta{VersionId: "2"},
+		})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, `W/"2"`, ifMatch)
+		assert.Equal(t, "2", res.Meta.VersionId)
+	})
+
+	t.Run("should fail if the resource changed in the meantime", func(t *testing.T) {
+		server := newServer("3")
+		defer server.Close()
+		client := NewApiClient(server.URL, "foo", "bar")
+
+		_, err := client.UpdateClient(context.TODO(), &Client{ResourceBase: ResourceBase{ID: "guild", VersionId: "2"}})
+
+		assert.ErrorIs(t, err, ResourceChangedError)
+		assert.ErrorContains(t, err, "/Client/guild resource changed since last refresh (expected version 2)")
+	})
+
+	t.Run("should overwrite blindly if disabled or the version isn't known", func(t *testing.T) {
+		server := newServer("3")
+		defer server.Close()
+		client := NewApiClient(server.URL, "foo", "bar")
+
+		_, err := client.UpdateClient(context.TODO(), &Client{ResourceBase: ResourceBase{ID: "guild"}})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "", ifMatch)
+
+		client.OptimisticLocking = false
+		_, err = client.UpdateClient(context.TODO(), &Client{ResourceBase: ResourceBase{ID: "guild", VersionId: "2"}})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "", ifMatch)
+	})
+}
+
+func TestApiClientThrottling(t *testing.T) {
+	t.Run("should limit the requests in flight", func(t *testing.T) {
+		var inFlight, maxInFlight atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				seen := maxInFlight.Load()
+				if current <= seen || maxInFlight.CompareAndSwap(seen, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			w.Write([]byte(`{"name": "Nobby", "value": 1}`))
+		}))
+		defer server.Close()
+		client := NewApiClient(server.URL, "foo", "bar")
+		client.MaxConcurrentRequests = 2
+
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Equal(t, nil, client.get(context.TODO(), "/endpoint", &TestResponse{}))
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(2), maxInFlight.Load())
+	})
+
+	t.Run("should limit the rate of requests", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"name": "Colon", "value": 2}`))
+		}))
+		defer server.Close()
+		client := NewApiClient(server.URL, "foo", "bar")
+		client.RequestsPerSecond = 20
+
+		start := time.Now()
+		for range 5 {
+			assert.Equal(t, nil, client.get(context.TODO(), "/endpoint", &TestResponse{}))
+		}
+
+		// the first request is free, the other four are 50ms apart
+		assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+	})
+
+	t.Run("should stop waiting when cancelled", func(t *testing.T) {
+		client := NewApiClient("http://localhost:1", "foo", "bar")
+		client.RequestsPerSecond = 0.001
+		ctx, cancel := context.WithCancel(context.TODO())
+		cancel()
+
+		err := client.get(ctx, "/endpoint", &TestResponse{})
+
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
+
+func TestEnums(t *testing.T) {
+	t.Run("should parse known values only", func(t *testing.T) {
+		engine, err := ParseAccessPolicyEngine("matcho")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, AccessPolicyEngineMatcho, engine)
+
+		_, err = ParseAccessPolicyEngine("prolog")
+		assert.ErrorIs(t, err, ErrInvalidAccessPolicyEngine)
+		assert.ErrorContains(t, err, `"prolog"`)
+	})
+
+	t.Run("should keep unknown values the server responds with", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"resourceType": "AccessPolicy", "id": "ap", "engine": "prolog"}`))
+		}))
+		defer server.Close()
+		apiClient := NewApiClient(server.URL, "foo", "bar")
+
+		policy, err := apiClient.GetAccessPolicy(context.TODO(), "ap")
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, AccessPolicyEngine("prolog"), policy.Engine)
+		assert.False(t, policy.Engine.IsKnown())
+		marshalled, err := json.Marshal(policy)
+		assert.Equal(t, nil, err)
+		assert.Contains(t, string(marshalled), `"engine":"prolog"`)
+	})
+}
+
+func TestEvaluatePolicies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/auth/test-policy", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"request": {"request-method": "get", "uri": "/fhir/Patient", "params": {"name": "ann"}}, "user": {"id": "ann"}}`, string(body))
+		w.Write([]byte(`{"operation": {"id": "FhirSearch"}, "policies": {
+			"practitioners": {"eval-result": true}, "admins": {"eval-result": false}, "patients": {"eval-result": true}}}`))
+	}))
+	defer server.Close()
+	apiClient := NewApiClient(server.URL, "foo", "bar")
+
+	evaluation, err := apiClient.EvaluatePolicies(context.TODO(), &PolicyEvaluationRequest{
+		Request: PolicyEvaluationHttpRequest{Method: "get", Uri: "/fhir/Patient", Params: map[string]string{"name": "ann"}},
+		User:    &IdReference{ID: "ann"},
+	})
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "FhirSearch", evaluation.Operation.ID)
+	assert.True(t, evaluation.Allowed())
+	assert.Equal(t, []string{"patients", "practitioners"}, evaluation.MatchedPolicies())
+}
@@ -0,0 +1,56 @@
+package aidbox
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// APIError is returned for every unsuccessful (non-2xx) response from aidbox
+type APIError struct {
+	StatusCode int
+	Status     string
+	Method     string
+	URL        string
+	// Issues are parsed from the response body if it's an OperationOutcome, empty otherwise
+	Issues []OperationOutcomeIssue
+	// Body is the raw response body
+	Body []byte
+
+	// requestDetails are the headers and body of the request, only captured during acceptance testing as they may
+	// contain secrets
+	requestDetails string
+}
+
+func (e *APIError) Error() string {
+	var prettyResponse bytes.Buffer
+	jsonParseErr := json.Indent(&prettyResponse, e.Body, "", "  ")
+	if jsonParseErr != nil {
+		return fmt.Sprintf("unexpected status code (%d) received: %s\n\n"+
+			"===== %s %s =====\n\n"+
+			"===== RESPONSE BODY =====\n"+
+			"%s\n",
+			e.StatusCode,
+			e.Status,
+			e.Method, e.URL,
+			string(e.Body))
+	}
+
+	return fmt.Sprintf("unexpected status code (%d) received: %s\n\n"+
+		"===== %s %s =====%s\n\n"+
+		"===== RESPONSE BODY =====\n"+
+		"%s\n",
+		e.StatusCode,
+		e.Status,
+		e.Method, e.URL,
+		e.requestDetails,
+		prettyResponse.String())
+}
+
+// Is makes errors.Is(err, NotFoundError) hold for 404 responses of any request, not just the ones of get, and
+// errors.Is(err, ResourceChangedError) for updates failing their If-Match precondition
+func (e *APIError) Is(target error) bool {
+	return (target == NotFoundError && e.StatusCode == http.StatusNotFound) ||
+		(target == ResourceChangedError && e.StatusCode == http.StatusPreconditionFailed)
+}
@@ -0,0 +1,123 @@
+package aidbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+)
+
+type AuthMethod string
+
+const (
+	// AuthMethodBasic sends the client id and secret as basic auth credentials on every request
+	AuthMethodBasic AuthMethod = "basic"
+	// AuthMethodClientCredentials exchanges the client id and secret for a bearer token using the OAuth2
+	// client_credentials grant, see https://docs.aidbox.app/modules/security-and-access-control/auth/client-credentials-grant
+	AuthMethodClientCredentials AuthMethod = "client_credentials"
+)
+
+// AuthMethods are the ways the provider can authenticate
+var AuthMethods = EnumValues[AuthMethod]{
+	AuthMethodBasic,
+	AuthMethodClientCredentials,
+}
+
+const ErrInvalidAuthMethod AidboxError = "Invalid auth method"
+
+func ParseAuthMethod(s string) (AuthMethod, error) {
+	return AuthMethods.parse(s, ErrInvalidAuthMethod)
+}
+
+// tokenRefreshMargin is how long before its expiry a cached access token is replaced, so that a token never expires
+// while a request using it is in flight
+const tokenRefreshMargin = 30 * time.Second
+
+type accessToken struct {
+	value string
+	// refreshAt is zero if the server didn't tell us when the token expires, in which case it's used until rejected
+	refreshAt time.Time
+}
+
+func (t *accessToken) isValid(now time.Time) bool {
+	return t != nil && (t.refreshAt.IsZero() || now.Before(t.refreshAt))
+}
+
+type tokenRequest struct {
+	GrantType    string `json:"grant_type"`
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// getAccessToken returns the cached access token, requesting a new one if there's none yet or it's about to expire
+func (apiClient *ApiClient) getAccessToken(ctx context.Context) (string, error) {
+	apiClient.tokenLock.Lock()
+	defer apiClient.tokenLock.Unlock()
+	if apiClient.token.isValid(time.Now()) {
+		return apiClient.token.value, nil
+	}
+	token, err := apiClient.requestAccessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	apiClient.token = token
+	return token.value, nil
+}
+
+func (apiClient *ApiClient) invalidateAccessToken() {
+	apiClient.tokenLock.Lock()
+	defer apiClient.tokenLock.Unlock()
+	apiClient.token = nil
+}
+
+func (apiClient *ApiClient) requestAccessToken(ctx context.Context) (*accessToken, error) {
+	requestBody := tokenRequest{
+		GrantType:    "client_credentials",
+		ClientId:     apiClient.Username,
+		ClientSecret: apiClient.Password,
+	}
+	buf := bytes.Buffer{}
+	err := json.NewEncoder(&buf).Encode(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiClient.URL+"/auth/token", &buf)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	issuedAt := time.Now()
+	// issuing a token changes nothing on the server, so it's retried like an idempotent request
+	res, body, err := apiClient.retry(req, true, apiClient.doThrottled)
+	if err != nil {
+		return nil, err
+	}
+	if !isAlright(res.StatusCode) {
+		// never echo the secret back, even in the detailed errors printed during testing
+		requestBody.ClientSecret = "<redacted>"
+		return nil, errorToTerraform(req, res, requestBody, body)
+	}
+	response := tokenResponse{}
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, err
+	}
+	if response.AccessToken == "" {
+		return nil, errors.New("no access_token in the response of the token endpoint")
+	}
+	token := &accessToken{value: response.AccessToken}
+	if response.ExpiresIn > 0 {
+		lifetime := time.Duration(response.ExpiresIn) * time.Second
+		token.refreshAt = issuedAt.Add(lifetime - min(tokenRefreshMargin, lifetime/2))
+	}
+	return token, nil
+}
@@ -0,0 +1,20 @@
+package aidbox
+
+import (
+	"context"
+	"fmt"
+)
+
+// SubmitTransaction applies the entries atomically, either all of them succeed or none. The entries of the returned
+// transaction-response are in the same order as the submitted ones.
+func (apiClient *ApiClient) SubmitTransaction(ctx context.Context, entries []BundleEntry) (*Bundle, error) {
+	response := &Bundle{}
+	err := apiClient.post(ctx, &Bundle{ResourceType: "Bundle", Type: "transaction", Entry: entries}, "/", response)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Entry) != len(entries) {
+		return nil, fmt.Errorf("expected %d entries in the transaction response but got %d", len(entries), len(response.Entry))
+	}
+	return response, nil
+}
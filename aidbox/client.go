@@ -1,96 +1,105 @@
 package aidbox
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"log"
 )
 
 type Client struct {
 	ResourceBase
-	Secret     string      `json:"secret"`
+	Secret     string      `json:"secret,omitempty"`
 	GrantTypes []GrantType `json:"grant_types"`
+	// FirstParty clients are trusted apps, the user isn't asked to grant them access
+	FirstParty bool         `json:"first_party,omitempty"`
+	Auth       *ClientAuth  `json:"auth,omitempty"`
+	Smart      *ClientSmart `json:"smart,omitempty"`
 }
 
 func (*Client) GetResourcePath() string {
 	return "Client"
 }
 
-type GrantType int
+// ClientAuth configures each OAuth 2.0 flow the client is allowed to use
+type ClientAuth struct {
+	AuthorizationCode *ClientAuthFlow `json:"authorization_code,omitempty"`
+	ClientCredentials *ClientAuthFlow `json:"client_credentials,omitempty"`
+	Password          *ClientAuthFlow `json:"password,omitempty"`
+	Implicit          *ClientAuthFlow `json:"implicit,omitempty"`
+}
+
+// ClientAuthFlow is the configuration of a single flow, not every field applies to every flow
+type ClientAuthFlow struct {
+	RedirectUri string `json:"redirect_uri,omitempty"`
+	// AccessTokenExpiration is in seconds, the server's default if zero
+	AccessTokenExpiration int         `json:"access_token_expiration,omitempty"`
+	TokenFormat           TokenFormat `json:"token_format,omitempty"`
+	RefreshToken          bool        `json:"refresh_token,omitempty"`
+	SecretRequired        bool        `json:"secret_required,omitempty"`
+	Pkce                  bool        `json:"pkce,omitempty"`
+}
+
+// ClientSmart describes a SMART on FHIR app
+type ClientSmart struct {
+	LaunchUri   string `json:"launch_uri,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type GrantType string
 
 const (
-	GrantTypeBasic GrantType = iota
-	//GrantTypeAuthorizationCode
-	//GrantTypeCode
-	//GrantTypePassword
-	//GrantTypeClientCredentials
-	//GrantTypeImplicit
-	//GrantTypeRefreshToken
+	GrantTypeBasic             GrantType = "basic"
+	GrantTypeAuthorizationCode GrantType = "authorization_code"
+	GrantTypeCode              GrantType = "code"
+	GrantTypePassword          GrantType = "password"
+	GrantTypeClientCredentials GrantType = "client_credentials"
+	GrantTypeImplicit          GrantType = "implicit"
+	GrantTypeRefreshToken      GrantType = "refresh_token"
 )
 
-func (g GrantType) ToString() string {
-	switch g {
-	case GrantTypeBasic:
-		return "basic"
-		//case GrantTypeAuthorizationCode:
-		//	return "authorization_code"
-		//case GrantTypeCode:
-		//	return "code"
-		//case GrantTypePassword:
-		//	return "password"
-		//case GrantTypeClientCredentials:
-		//	return "client_credentials"
-		//case GrantTypeImplicit:
-		//	return "implicit"
-		//case GrantTypeRefreshToken:
-		//	return "refresh_token"
-	}
-	log.Panicf("Unexpected GrantType %d\n", g)
-	return ""
+// GrantTypes are the grant types the provider supports
+var GrantTypes = EnumValues[GrantType]{
+	GrantTypeBasic,
+	GrantTypeAuthorizationCode,
+	GrantTypeCode,
+	GrantTypePassword,
+	GrantTypeClientCredentials,
+	GrantTypeImplicit,
+	GrantTypeRefreshToken,
 }
 
 const ErrInvalidGrantType AidboxError = "Unsupported grant type"
 
 func ParseGrantType(typeString string) (GrantType, error) {
-	switch typeString {
-	case "basic":
-		return GrantTypeBasic, nil
-	//case "authorization_code":
-	//	return GrantTypeAuthorizationCode, nil
-	//case "code":
-	//	return GrantTypeCode, nil
-	//case "password":
-	//	return GrantTypePassword, nil
-	//case "client_credentials":
-	//	return GrantTypeClientCredentials, nil
-	//case "implicit":
-	//	return GrantTypeImplicit, nil
-	//case "refresh_token":
-	//	return GrantTypeRefreshToken, nil
-	default:
-		return 0, ErrInvalidGrantType
-	}
-}
-
-func (g GrantType) MarshalJSON() ([]byte, error) {
-	buffer := bytes.NewBufferString(`"`)
-	buffer.WriteString(g.ToString())
-	buffer.WriteString(`"`)
-	return buffer.Bytes(), nil
-}
-
-func (g *GrantType) UnmarshalJSON(b []byte) error {
-	var j string
-	if err := json.Unmarshal(b, &j); err != nil {
-		return err
-	}
-	tt, err := ParseGrantType(j)
-	if err != nil {
-		return err
-	}
-	*g = tt
-	return nil
+	return GrantTypes.parse(typeString, ErrInvalidGrantType)
+}
+
+func (g GrantType) IsKnown() bool {
+	return GrantTypes.Contains(g)
+}
+
+// TokenFormat of the issued access tokens. Opaque is the server's default, a flow without a token format issues
+// opaque tokens.
+type TokenFormat string
+
+const (
+	TokenFormatOpaque TokenFormat = "opaque"
+	TokenFormatJwt    TokenFormat = "jwt"
+)
+
+// TokenFormats are the token formats the provider supports
+var TokenFormats = EnumValues[TokenFormat]{
+	TokenFormatOpaque,
+	TokenFormatJwt,
+}
+
+const ErrInvalidTokenFormat AidboxError = "Unsupported token format"
+
+func ParseTokenFormat(formatString string) (TokenFormat, error) {
+	return TokenFormats.parse(formatString, ErrInvalidTokenFormat)
+}
+
+func (t TokenFormat) IsKnown() bool {
+	return TokenFormats.Contains(t)
 }
 
 func (apiClient *ApiClient) CreateClient(ctx context.Context, client *Client) (*Client, error) {
@@ -103,6 +112,10 @@ func (apiClient *ApiClient) GetClient(ctx context.Context, id string) (*Client,
 	return response, apiClient.getResource(ctx, id, response)
 }
 
+func (apiClient *ApiClient) ListClients(ctx context.Context) ([]*Client, error) {
+	return listResources[Client](ctx, apiClient, (&Client{}).GetResourcePath())
+}
+
 func (apiClient *ApiClient) UpdateClient(ctx context.Context, q *Client) (*Client, error) {
 	response := &Client{}
 	return response, apiClient.updateResource(ctx, q, response)
@@ -0,0 +1,34 @@
+package aidbox
+
+import "fmt"
+
+// EnumValues are the values of a string enum known to the provider. Values the server responds with are kept as they
+// are even if they aren't known, e.g. because they were added in a later version of aidbox or set by another client,
+// so that reading a resource never fails on them. Only values coming from the configuration are parsed.
+type EnumValues[T ~string] []T
+
+// Contains tells whether the value is a known one
+func (values EnumValues[T]) Contains(value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Strings lists the known values, e.g. for validating the configuration
+func (values EnumValues[T]) Strings() []string {
+	var strs []string
+	for _, v := range values {
+		strs = append(strs, string(v))
+	}
+	return strs
+}
+
+func (values EnumValues[T]) parse(s string, invalid AidboxError) (T, error) {
+	if !values.Contains(T(s)) {
+		return "", fmt.Errorf("%w %q", invalid, s)
+	}
+	return T(s), nil
+}
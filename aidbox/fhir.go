@@ -3,9 +3,49 @@ package aidbox
 import "encoding/json"
 
 type Bundle struct {
+	ResourceType string `json:"resourceType,omitempty"`
+	// Type is e.g. searchset, transaction or transaction-response
+	Type  string        `json:"type,omitempty"`
 	Entry []BundleEntry `json:"entry"`
+	Link  []BundleLink  `json:"link,omitempty"`
+}
+
+// BundleLink links to related pages of a search result, e.g. the "next" one
+type BundleLink struct {
+	Relation string `json:"relation"`
+	Url      string `json:"url"`
 }
 
 type BundleEntry struct {
-	Resource json.RawMessage `json:"resource"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+	// Request is what to do with the entry in a transaction
+	Request *BundleEntryRequest `json:"request,omitempty"`
+	// Response is the outcome of the entry in a transaction-response
+	Response *BundleEntryResponse `json:"response,omitempty"`
+}
+
+type BundleEntryRequest struct {
+	Method string `json:"method"`
+	// Url is relative to the base, e.g. Patient/42
+	Url string `json:"url"`
+}
+
+type BundleEntryResponse struct {
+	Status   string `json:"status"`
+	Location string `json:"location,omitempty"`
+}
+
+// OperationOutcome https://hl7.org/fhir/R4/operationoutcome.html, aidbox describes most errors with one
+type OperationOutcome struct {
+	ResourceType string                  `json:"resourceType"`
+	Issue        []OperationOutcomeIssue `json:"issue"`
+}
+
+type OperationOutcomeIssue struct {
+	// Severity is one of fatal | error | warning | information
+	Severity    string `json:"severity"`
+	Code        string `json:"code"`
+	Diagnostics string `json:"diagnostics,omitempty"`
+	// Expression are FHIRPath expressions of the elements the issue is about, e.g. "AccessPolicy.engine"
+	Expression []string `json:"expression,omitempty"`
 }
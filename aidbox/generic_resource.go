@@ -4,8 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"path"
+	"slices"
+	"strings"
 )
 
 // GenericResource
@@ -15,24 +19,55 @@ type GenericResource struct {
 	// this is the combined ResourceType/ID, otherwise terraform doesn't know how to destroy this thing.
 	ResourceTypeAndId string
 	ResourceContent   json.RawMessage
+	// Meta is the metadata of a resource read from the server, its versionId is also the version an update expects to
+	// replace. It's never sent, the content is sent as it is.
+	Meta *ResourceBaseMeta
+	// Yaml is the content as it was written in YAML, which is sent instead of ResourceContent if set. ResourceContent
+	// still has to be the same content in JSON.
+	Yaml string
+	// ConditionalCreateQuery makes the create a no-op if a resource matching the search query already exists
+	ConditionalCreateQuery string
+	// ConditionalUpdateQuery makes the create update the resource matching the search query if there is one
+	ConditionalUpdateQuery string
 }
 
 func (g *GenericResource) MarshalJSON() ([]byte, error) {
 	return g.ResourceContent, nil
 }
 
+// requestBody is what's sent to create or update the resource
+func (g *GenericResource) requestBody() interface{} {
+	if g.Yaml != "" {
+		return yamlBody(g.Yaml)
+	}
+	return g
+}
+
 func (g *GenericResource) UnmarshalJSON(b []byte) error {
 	resourceTypeAndId, err := GetResourceTypeAndId(b)
 	if err != nil {
 		return err
 	}
+	var base ResourceBase
+	err = json.Unmarshal(b, &base)
+	if err != nil {
+		return err
+	}
 	*g = GenericResource{
-		resourceTypeAndId,
-		b,
+		ResourceTypeAndId: resourceTypeAndId,
+		ResourceContent:   b,
+		Meta:              base.Meta,
 	}
 	return nil
 }
 
+func (g *GenericResource) versionId() string {
+	if g.Meta == nil {
+		return ""
+	}
+	return g.Meta.VersionId
+}
+
 func GetResourceTypeAndId(b []byte) (string, error) {
 	h, err := parseToMap(b)
 	if err != nil {
@@ -46,10 +81,19 @@ func GetResourceTypeAndId(b []byte) (string, error) {
 	if !ok {
 		return "", errors.New("no 'id' field in JSON body")
 	}
-	return resourceType.(string) + "/" + idPart.(string), nil
+	typeString, ok := resourceType.(string)
+	if !ok {
+		return "", errors.New("'resourceType' must be a string")
+	}
+	idString, ok := idPart.(string)
+	if !ok {
+		return "", errors.New("'id' must be a string")
+	}
+	return typeString + "/" + idString, nil
 }
 
-func getResourceType(b []byte) (string, error) {
+// GetResourceType reads the resourceType of the resource
+func GetResourceType(b []byte) (string, error) {
 	h, err := parseToMap(b)
 	if err != nil {
 		return "", err
@@ -58,7 +102,11 @@ func getResourceType(b []byte) (string, error) {
 	if !ok {
 		return "", errors.New("no 'resourceType' field in JSON body")
 	}
-	return resourceType.(string), nil
+	typeString, ok := resourceType.(string)
+	if !ok {
+		return "", errors.New("'resourceType' must be a string")
+	}
+	return typeString, nil
 }
 
 func parseToMap(b []byte) (map[string]any, error) {
@@ -74,7 +122,7 @@ func parseToMap(b []byte) (map[string]any, error) {
 // the resourcetype/ID, it doesn't fit.
 func (apiClient *ApiClient) CreateGenericResource(ctx context.Context, genericResource *GenericResource) (*GenericResource, error) {
 	responseTarget := &GenericResource{}
-	resourceType, err := getResourceType(genericResource.ResourceContent)
+	resourceType, err := GetResourceType(genericResource.ResourceContent)
 	if err != nil {
 		return nil, err
 	}
@@ -87,10 +135,20 @@ func (apiClient *ApiClient) CreateGenericResource(ctx context.Context, genericRe
 			// couldn't parse an ID from the resource; don't panic, the user just didn't specify one, we'll use POST
 		}
 	}
-	if resourceTypeAndId != "" {
-		err = apiClient.put(ctx, genericResource, path.Join("/", resourceTypeAndId), responseTarget)
-	} else {
-		err = apiClient.post(ctx, genericResource, path.Join("/", resourceType), responseTarget)
+	requestBody := genericResource.requestBody()
+	switch {
+	case genericResource.ConditionalCreateQuery != "":
+		header := http.Header{}
+		header.Set("If-None-Exist", genericResource.ConditionalCreateQuery)
+		err = apiClient.sendWithHeader(ctx, requestBody, path.Join("/", resourceType), responseTarget, http.MethodPost, header)
+		err = conditionalError(err, resourceType, genericResource.ConditionalCreateQuery)
+	case genericResource.ConditionalUpdateQuery != "":
+		err = apiClient.put(ctx, requestBody, path.Join("/", resourceType)+"?"+genericResource.ConditionalUpdateQuery, responseTarget)
+		err = conditionalError(err, resourceType, genericResource.ConditionalUpdateQuery)
+	case resourceTypeAndId != "":
+		err = apiClient.put(ctx, requestBody, path.Join("/", resourceTypeAndId), responseTarget)
+	default:
+		err = apiClient.post(ctx, requestBody, path.Join("/", resourceType), responseTarget)
 	}
 
 	if err != nil {
@@ -99,6 +157,16 @@ func (apiClient *ApiClient) CreateGenericResource(ctx context.Context, genericRe
 	return responseTarget, nil
 }
 
+// conditionalError explains the precondition failure of a conditional create or update, which aidbox responds with if
+// the query matches more than one resource
+func conditionalError(err error, resourceType string, query string) error {
+	var apiError *APIError
+	if errors.As(err, &apiError) && apiError.StatusCode == http.StatusPreconditionFailed {
+		return fmt.Errorf("more than one %s matches %s, the query has to match at most one: %w", resourceType, query, err)
+	}
+	return err
+}
+
 func (apiClient *ApiClient) GetGenericResource(ctx context.Context, resourceTypeAndId string) (*GenericResource, error) {
 	responseTarget := &GenericResource{}
 	err := apiClient.get(ctx, path.Join("/", resourceTypeAndId), responseTarget)
@@ -108,9 +176,13 @@ func (apiClient *ApiClient) GetGenericResource(ctx context.Context, resourceType
 	return responseTarget, nil
 }
 
+func (apiClient *ApiClient) ListGenericResources(ctx context.Context, resourceType string) ([]*GenericResource, error) {
+	return listResources[GenericResource](ctx, apiClient, resourceType)
+}
+
 func (apiClient *ApiClient) UpdateGenericResource(ctx context.Context, q *GenericResource) (*GenericResource, error) {
 	responseTarget := &GenericResource{}
-	err := apiClient.put(ctx, q, path.Join("/", q.ResourceTypeAndId), responseTarget)
+	err := apiClient.putVersion(ctx, q.requestBody(), path.Join("/", q.ResourceTypeAndId), q.versionId(), responseTarget)
 	if err != nil {
 		return nil, err
 	}
@@ -120,3 +192,109 @@ func (apiClient *ApiClient) UpdateGenericResource(ctx context.Context, q *Generi
 func (apiClient *ApiClient) DeleteGenericResource(ctx context.Context, resourceTypeAndId string) error {
 	return apiClient.send(ctx, struct{}{}, path.Join("/", resourceTypeAndId), &struct{}{}, http.MethodDelete)
 }
+
+// PurgeableResourceTypes are the types aidbox has the $purge operation for
+var PurgeableResourceTypes = []string{"Patient"}
+
+const ErrNotPurgeable AidboxError = "Only patients can be purged"
+
+// PurgeGenericResource removes the patient along with its history and the data in its compartment with the $purge
+// operation, unlike a delete after which the history is still kept
+func (apiClient *ApiClient) PurgeGenericResource(ctx context.Context, resourceTypeAndId string) error {
+	resourceType, _, _ := strings.Cut(resourceTypeAndId, "/")
+	if !slices.Contains(PurgeableResourceTypes, resourceType) {
+		return fmt.Errorf("%w, not %s", ErrNotPurgeable, resourceTypeAndId)
+	}
+	return apiClient.post(ctx, struct{}{}, path.Join("/fhir", resourceTypeAndId, "$purge"), &json.RawMessage{})
+}
+
+// FindReferencingResources lists the resources with a reference to the given one, as ResourceType/id
+func (apiClient *ApiClient) FindReferencingResources(ctx context.Context, resourceTypeAndId string) ([]string, error) {
+	resourceType, id, _ := strings.Cut(resourceTypeAndId, "/")
+	resources, err := apiClient.SearchResources(ctx, resourceType, url.Values{"_id": {id}, "_revinclude": {"*:*"}})
+	if err != nil {
+		return nil, err
+	}
+	var referencing []string
+	for _, resource := range resources {
+		typeAndId, err := GetResourceTypeAndId(resource)
+		if err != nil {
+			return nil, err
+		}
+		if typeAndId != resourceTypeAndId {
+			referencing = append(referencing, typeAndId)
+		}
+	}
+	return referencing, nil
+}
+
+// ValidateResource checks the resource against its profiles with the FHIR $validate operation, without storing it.
+// Issues found are returned in the outcome rather than as an error, which is reserved for failing to validate at all.
+func (apiClient *ApiClient) ValidateResource(ctx context.Context, resourceContent json.RawMessage) (*OperationOutcome, error) {
+	resourceType, err := GetResourceType(resourceContent)
+	if err != nil {
+		return nil, err
+	}
+	response := &OperationOutcome{}
+	err = apiClient.post(ctx, resourceContent, path.Join("/fhir", resourceType, "$validate"), response)
+	var apiError *APIError
+	if errors.As(err, &apiError) && len(apiError.Issues) > 0 {
+		return &OperationOutcome{ResourceType: "OperationOutcome", Issue: apiError.Issues}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// SearchResources runs a FHIR search for resources of the type, following the pages of the result until every match
+// is collected
+func (apiClient *ApiClient) SearchResources(ctx context.Context, resourceType string, params url.Values) ([]json.RawMessage, error) {
+	relativePath := path.Join("/fhir", resourceType)
+	if len(params) > 0 {
+		relativePath += "?" + params.Encode()
+	}
+	return apiClient.searchAll(ctx, relativePath)
+}
+
+func (apiClient *ApiClient) searchAll(ctx context.Context, relativePath string) ([]json.RawMessage, error) {
+	var resources []json.RawMessage
+	// guard against a server linking back to a page we've already seen
+	visited := map[string]bool{}
+	for relativePath != "" && !visited[relativePath] {
+		visited[relativePath] = true
+		response := &Bundle{}
+		err := apiClient.get(ctx, relativePath, response)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range response.Entry {
+			resources = append(resources, entry.Resource)
+		}
+		relativePath, err = apiClient.nextPage(response)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resources, nil
+}
+
+// nextPage returns the path of the next page of the search result relative to the client's URL, or "" if it was the
+// last page. Aidbox links with its own idea of its base URL, which isn't necessarily the one we reach it on.
+func (apiClient *ApiClient) nextPage(bundle *Bundle) (string, error) {
+	for _, link := range bundle.Link {
+		if link.Relation != "next" {
+			continue
+		}
+		base := strings.TrimSuffix(apiClient.URL, "/")
+		if strings.HasPrefix(link.Url, base+"/") {
+			return strings.TrimPrefix(link.Url, base), nil
+		}
+		next, err := url.Parse(link.Url)
+		if err != nil {
+			return "", err
+		}
+		return next.RequestURI(), nil
+	}
+	return "", nil
+}
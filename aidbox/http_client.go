@@ -0,0 +1,56 @@
+package aidbox
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// HTTPClientConfig describes how to reach an aidbox server, e.g. one behind an internal CA or requiring mTLS
+type HTTPClientConfig struct {
+	// CACertPEM are additional PEM encoded CA certificates to trust on top of the system ones
+	CACertPEM string
+	// ClientCertPEM and ClientKeyPEM are the PEM encoded certificate and private key presented for mTLS
+	ClientCertPEM string
+	ClientKeyPEM  string
+	// InsecureSkipVerify disables verifying the server certificate, only meant for local development
+	InsecureSkipVerify bool
+	// Timeout limits the time of a single request including reading the response, no limit if zero
+	Timeout time.Duration
+}
+
+// NewHTTPClient builds a client with its own transport, so that no connection or TLS state is shared with other
+// clients in the process, e.g. those of other aliased provider blocks.
+func NewHTTPClient(config HTTPClientConfig) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+	if config.CACertPEM != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(config.CACertPEM)) {
+			return nil, errors.New("no valid PEM encoded certificate found in the CA certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCertPEM != "" || config.ClientKeyPEM != "" {
+		if config.ClientCertPEM == "" || config.ClientKeyPEM == "" {
+			return nil, errors.New("both the client certificate and its key are required for mTLS")
+		}
+		certificate, err := tls.X509KeyPair([]byte(config.ClientCertPEM), []byte(config.ClientKeyPEM))
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.Timeout,
+	}, nil
+}
@@ -0,0 +1,137 @@
+package aidbox
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHTTPClient(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+		w.Write([]byte("{\"name\": \"Great A'Tuin\", \"value\": 4}"))
+	})
+	serverCAPEM := func(server *httptest.Server) string {
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	}
+	get := func(serverURL string, config HTTPClientConfig) error {
+		httpClient, err := NewHTTPClient(config)
+		if err != nil {
+			return err
+		}
+		client := NewApiClient(serverURL, "foo", "bar")
+		client.HTTPClient = httpClient
+		return client.get(context.TODO(), "/endpoint", &TestResponse{})
+	}
+
+	t.Run("should not trust a server signed by an unknown CA", func(t *testing.T) {
+		server := httptest.NewTLSServer(handler)
+		defer server.Close()
+
+		err := get(server.URL, HTTPClientConfig{})
+
+		assert.ErrorContains(t, err, "certificate signed by unknown authority")
+	})
+
+	t.Run("should trust a server signed by the given CA", func(t *testing.T) {
+		server := httptest.NewTLSServer(handler)
+		defer server.Close()
+
+		err := get(server.URL, HTTPClientConfig{CACertPEM: serverCAPEM(server)})
+
+		assert.Equal(t, nil, err)
+	})
+
+	t.Run("should skip verifying the server if asked to", func(t *testing.T) {
+		server := httptest.NewTLSServer(handler)
+		defer server.Close()
+
+		err := get(server.URL, HTTPClientConfig{InsecureSkipVerify: true})
+
+		assert.Equal(t, nil, err)
+	})
+
+	t.Run("should present the client certificate for mTLS", func(t *testing.T) {
+		clientCertPEM, clientKeyPEM := generateCertificate(t)
+		clientCAs := x509.NewCertPool()
+		clientCAs.AppendCertsFromPEM([]byte(clientCertPEM))
+		server := httptest.NewUnstartedServer(handler)
+		server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+		server.StartTLS()
+		defer server.Close()
+
+		err := get(server.URL, HTTPClientConfig{CACertPEM: serverCAPEM(server)})
+		assert.Error(t, err)
+
+		err = get(server.URL, HTTPClientConfig{
+			CACertPEM:     serverCAPEM(server),
+			ClientCertPEM: clientCertPEM,
+			ClientKeyPEM:  clientKeyPEM,
+		})
+		assert.Equal(t, nil, err)
+	})
+
+	t.Run("should time out slow requests", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+			handler(w, r)
+		}))
+		defer server.Close()
+
+		err := get(server.URL, HTTPClientConfig{Timeout: 50 * time.Millisecond})
+
+		assert.ErrorContains(t, err, "Client.Timeout exceeded")
+	})
+
+	t.Run("should reject invalid configuration", func(t *testing.T) {
+		_, err := NewHTTPClient(HTTPClientConfig{CACertPEM: "not a certificate"})
+		assert.Error(t, err)
+
+		clientCertPEM, _ := generateCertificate(t)
+		_, err = NewHTTPClient(HTTPClientConfig{ClientCertPEM: clientCertPEM})
+		assert.ErrorContains(t, err, "both the client certificate and its key are required")
+	})
+
+	t.Run("should not share the transport between clients", func(t *testing.T) {
+		assert.NotSame(t, NewApiClient("", "", "").HTTPClient.Transport, NewApiClient("", "", "").HTTPClient.Transport)
+		assert.NotSame(t, http.DefaultTransport, NewApiClient("", "", "").HTTPClient.Transport)
+	})
+}
+
+// generateCertificate creates a self-signed client certificate, returning the PEM encoded certificate and key
+func generateCertificate(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "terraform"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
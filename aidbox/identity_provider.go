@@ -2,8 +2,6 @@ package aidbox
 
 import (
 	"context"
-	"encoding/json"
-	"log"
 )
 
 type IdentityProviderClient struct {
@@ -27,52 +25,27 @@ func (g *IdentityProvider) GetResourcePath() string {
 	return "IdentityProvider"
 }
 
-type IdentityProviderUserinfoSource int
+type IdentityProviderUserinfoSource string
 
 const (
-	UserinfoSourceIdToken IdentityProviderUserinfoSource = iota
-	UserinfoSourceUserinfoEndpoint
+	UserinfoSourceIdToken          IdentityProviderUserinfoSource = "id-token"
+	UserinfoSourceUserinfoEndpoint IdentityProviderUserinfoSource = "userinfo-endpoint"
 )
 
-func (g IdentityProviderUserinfoSource) ToString() string {
-	switch g {
-	case UserinfoSourceIdToken:
-		return "id-token"
-	case UserinfoSourceUserinfoEndpoint:
-		return "userinfo-endpoint"
-	}
-	log.Panicf("Unexpected IdentityProviderUserinfoSource %d\n", g)
-	return ""
+// UserinfoSources are the userinfo sources the provider supports
+var UserinfoSources = EnumValues[IdentityProviderUserinfoSource]{
+	UserinfoSourceIdToken,
+	UserinfoSourceUserinfoEndpoint,
 }
 
 const ErrInvalidUserinfoSource AidboxError = "Invalid userinfo-source"
 
 func ParseUserinfoSource(s string) (IdentityProviderUserinfoSource, error) {
-	switch s {
-	case UserinfoSourceIdToken.ToString():
-		return UserinfoSourceIdToken, nil
-	case UserinfoSourceUserinfoEndpoint.ToString():
-		return UserinfoSourceUserinfoEndpoint, nil
-	default:
-		return 0, ErrInvalidUserinfoSource
-	}
+	return UserinfoSources.parse(s, ErrInvalidUserinfoSource)
 }
 
-func (g IdentityProviderUserinfoSource) MarshalJSON() ([]byte, error) {
-	return []byte("\"" + g.ToString() + "\""), nil
-}
-
-func (g *IdentityProviderUserinfoSource) UnmarshalJSON(b []byte) error {
-	var j string
-	if err := json.Unmarshal(b, &j); err != nil {
-		return err
-	}
-	tt, err := ParseUserinfoSource(j)
-	if err != nil {
-		return err
-	}
-	*g = tt
-	return nil
+func (g IdentityProviderUserinfoSource) IsKnown() bool {
+	return UserinfoSources.Contains(g)
 }
 
 func (apiClient *ApiClient) CreateIdentityProvider(ctx context.Context, identityProvider *IdentityProvider) (*IdentityProvider, error) {
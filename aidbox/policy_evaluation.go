@@ -0,0 +1,60 @@
+package aidbox
+
+import (
+	"context"
+	"sort"
+)
+
+// PolicyEvaluationRequest describes a request for aidbox to evaluate its access policies against, without the request
+// being made
+type PolicyEvaluationRequest struct {
+	Request PolicyEvaluationHttpRequest `json:"request"`
+	User    *IdReference                `json:"user,omitempty"`
+	Client  *IdReference                `json:"client,omitempty"`
+}
+
+// IdReference refers to a resource whose type is known from where it's used
+type IdReference struct {
+	ID string `json:"id"`
+}
+
+type PolicyEvaluationHttpRequest struct {
+	Method  string            `json:"request-method"`
+	Uri     string            `json:"uri"`
+	Params  map[string]string `json:"params,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// PolicyEvaluation is the outcome of evaluating the access policies, by policy id
+type PolicyEvaluation struct {
+	Operation *IdReference                      `json:"operation,omitempty"`
+	Policies  map[string]PolicyEvaluationResult `json:"policies"`
+}
+
+type PolicyEvaluationResult struct {
+	EvalResult bool `json:"eval-result"`
+}
+
+// MatchedPolicies lists the ids of the policies granting the request, in order
+func (e *PolicyEvaluation) MatchedPolicies() []string {
+	matched := []string{}
+	for id, result := range e.Policies {
+		if result.EvalResult {
+			matched = append(matched, id)
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+// Allowed tells whether the request is granted, which it is if any of the policies grants it
+func (e *PolicyEvaluation) Allowed() bool {
+	return len(e.MatchedPolicies()) > 0
+}
+
+// EvaluatePolicies asks aidbox which of its access policies grant the request, see
+// https://docs.aidbox.app/modules/security-and-access-control/access-policies#debug-access-policies
+func (apiClient *ApiClient) EvaluatePolicies(ctx context.Context, request *PolicyEvaluationRequest) (*PolicyEvaluation, error) {
+	response := &PolicyEvaluation{}
+	return response, apiClient.post(ctx, request, "/auth/test-policy", response)
+}
@@ -5,12 +5,19 @@ import "time"
 type ResourceBase struct {
 	ID   string            `json:"id,omitempty"`
 	Meta *ResourceBaseMeta `json:"meta,omitempty"`
+	// VersionId is the version an update expects to replace. It's sent in the If-Match header rather than the body,
+	// the version of a resource read from the server is in Meta.
+	VersionId string `json:"-"`
 }
 
 func (a *ResourceBase) GetID() string {
 	return a.ID
 }
 
+func (a *ResourceBase) GetVersionId() string {
+	return a.VersionId
+}
+
 type ResourceBaseMeta struct {
 	CreatedAt   *time.Time `json:"createdAt,omitempty"`
 	LastUpdated *time.Time `json:"lastUpdated,omitempty"`
@@ -21,4 +28,5 @@ type ResourceBaseMeta struct {
 type Resource interface {
 	GetResourcePath() string
 	GetID() string
+	GetVersionId() string
 }
@@ -0,0 +1,115 @@
+package aidbox
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+)
+
+// Aidbox answers with these while it is (re)starting, e.g. after loading a module, or when it's overloaded. None of
+// them mean the request was processed, so it's safe to send an idempotent request again.
+var retryableStatusCodes = map[int]bool{
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+// do executes the request, retrying it with exponential backoff and jitter on transient failures up to MaxRetries
+// times. Only idempotent requests are retried, as a failed POST may have been processed before the failure.
+func (apiClient *ApiClient) do(req *http.Request) (*http.Response, []byte, error) {
+	return apiClient.retry(req, idempotentMethods[req.Method], apiClient.doAuthenticated)
+}
+
+// retry sends the request with send, and again on transient failures if it's safe to do so
+func (apiClient *ApiClient) retry(req *http.Request, idempotent bool, send func(*http.Request) (*http.Response, []byte, error)) (*http.Response, []byte, error) {
+	for attempt := 0; ; attempt++ {
+		res, body, err := send(req)
+		if attempt >= apiClient.MaxRetries || !isRetryable(idempotent, res, err) {
+			return res, body, err
+		}
+		wait := apiClient.backoff(attempt, res)
+		tflog.Warn(req.Context(), "Retrying aidbox request after transient failure", map[string]interface{}{
+			"method":  req.Method,
+			"url":     req.URL.String(),
+			"attempt": attempt + 1,
+			"wait":    wait.String(),
+			"failure": describeFailure(res, err),
+		})
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, nil, req.Context().Err()
+		case <-timer.C:
+		}
+		req, err = cloneRequest(req)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+func isRetryable(idempotent bool, res *http.Response, err error) bool {
+	if !idempotent {
+		return false
+	}
+	if err != nil {
+		// connection refused/reset and the likes, but not giving up because terraform was interrupted
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+	return retryableStatusCodes[res.StatusCode]
+}
+
+func describeFailure(res *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return res.Status
+}
+
+// backoff doubles the wait with every attempt between RetryMinWait and RetryMaxWait, with equal jitter so that
+// parallel requests don't hit a recovering server all at once. A Retry-After header from the server takes precedence
+// if it asks for a longer wait, still capped at RetryMaxWait.
+func (apiClient *ApiClient) backoff(attempt int, res *http.Response) time.Duration {
+	wait := apiClient.RetryMaxWait
+	if attempt < 32 {
+		wait = min(apiClient.RetryMinWait<<attempt, apiClient.RetryMaxWait)
+	}
+	if wait > 0 {
+		wait = wait/2 + rand.N(wait/2+1)
+	}
+	if res != nil {
+		if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok && retryAfter > wait {
+			wait = min(retryAfter, apiClient.RetryMaxWait)
+		}
+	}
+	return wait
+}
+
+// parseRetryAfter understands both forms of the header, delay in seconds and HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
@@ -1,10 +1,7 @@
 package aidbox
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"log"
 )
 
 // SearchParameter Aidbox customized representation of FHIR "SearchParameter"
@@ -21,85 +18,39 @@ func (*SearchParameter) GetResourcePath() string {
 	return "SearchParameter"
 }
 
-type SearchParameterType int
+type SearchParameterType string
 
 const (
-	SearchParameterTypeString SearchParameterType = iota
-	SearchParameterTypeNumber
-	SearchParameterTypeDate
-	SearchParameterTypeToken
-	SearchParameterTypeQuantity
-	SearchParameterTypeReference
-	SearchParameterTypeUri
-	SearchParameterTypeComposite
+	SearchParameterTypeString    SearchParameterType = "string"
+	SearchParameterTypeNumber    SearchParameterType = "number"
+	SearchParameterTypeDate      SearchParameterType = "date"
+	SearchParameterTypeToken     SearchParameterType = "token"
+	SearchParameterTypeQuantity  SearchParameterType = "quantity"
+	SearchParameterTypeReference SearchParameterType = "reference"
+	SearchParameterTypeUri       SearchParameterType = "uri"
+	SearchParameterTypeComposite SearchParameterType = "composite"
 )
 
-func (t SearchParameterType) ToString() string {
-	switch t {
-	case SearchParameterTypeString:
-		return "string"
-	case SearchParameterTypeNumber:
-		return "number"
-	case SearchParameterTypeDate:
-		return "date"
-	case SearchParameterTypeToken:
-		return "token"
-	case SearchParameterTypeQuantity:
-		return "quantity"
-	case SearchParameterTypeReference:
-		return "reference"
-	case SearchParameterTypeUri:
-		return "uri"
-	case SearchParameterTypeComposite:
-		return "composite"
-	}
-	log.Panicf("Unexpected SearchParameterType %d\n", t)
-	return ""
+// SearchParameterTypes are the search parameter types the provider supports
+var SearchParameterTypes = EnumValues[SearchParameterType]{
+	SearchParameterTypeString,
+	SearchParameterTypeNumber,
+	SearchParameterTypeDate,
+	SearchParameterTypeToken,
+	SearchParameterTypeQuantity,
+	SearchParameterTypeReference,
+	SearchParameterTypeUri,
+	SearchParameterTypeComposite,
 }
 
 const ErrInvalidSearchParameterType AidboxError = "Unsupported search parameter type"
 
 func ParseSearchParameterType(typeString string) (SearchParameterType, error) {
-	switch typeString {
-	case "string":
-		return SearchParameterTypeString, nil
-	case "number":
-		return SearchParameterTypeNumber, nil
-	case "date":
-		return SearchParameterTypeDate, nil
-	case "token":
-		return SearchParameterTypeToken, nil
-	case "quantity":
-		return SearchParameterTypeQuantity, nil
-	case "reference":
-		return SearchParameterTypeReference, nil
-	case "uri":
-		return SearchParameterTypeUri, nil
-	case "composite":
-		return SearchParameterTypeComposite, nil
-	default:
-		return 0, ErrInvalidSearchParameterType
-	}
+	return SearchParameterTypes.parse(typeString, ErrInvalidSearchParameterType)
 }
 
-func (t SearchParameterType) MarshalJSON() ([]byte, error) {
-	buffer := bytes.NewBufferString(`"`)
-	buffer.WriteString(t.ToString())
-	buffer.WriteString(`"`)
-	return buffer.Bytes(), nil
-}
-
-func (t *SearchParameterType) UnmarshalJSON(b []byte) error {
-	var j string
-	if err := json.Unmarshal(b, &j); err != nil {
-		return err
-	}
-	tt, err := ParseSearchParameterType(j)
-	if err != nil {
-		return err
-	}
-	*t = tt
-	return nil
+func (t SearchParameterType) IsKnown() bool {
+	return SearchParameterTypes.Contains(t)
 }
 
 func (apiClient *ApiClient) CreateSearchParameter(ctx context.Context, searchParameter *SearchParameter) (*SearchParameter, error) {
@@ -112,6 +63,10 @@ func (apiClient *ApiClient) GetSearchParameter(ctx context.Context, id string) (
 	return response, apiClient.getResource(ctx, id, response)
 }
 
+func (apiClient *ApiClient) ListSearchParameters(ctx context.Context) ([]*SearchParameter, error) {
+	return listResources[SearchParameter](ctx, apiClient, (&SearchParameter{}).GetResourcePath())
+}
+
 func (apiClient *ApiClient) UpdateSearchParameter(ctx context.Context, q *SearchParameter) (*SearchParameter, error) {
 	response := &SearchParameter{}
 	return response, apiClient.updateResource(ctx, q, response)
@@ -1,109 +1,75 @@
 package aidbox
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"log"
 )
 
 // SearchParameterV2 Represents the FHIR R4 spec "SearchParameter"
 type SearchParameterV2 struct {
 	ResourceBase
-	ResourceType string                `json:"resourceType,omitempty"`
-	Name         string                `json:"name"`
-	Type         SearchParameterTypeV2 `json:"type"`
-	Expression   string                `json:"expression"`
-	Description  string                `json:"description"`
-	Url          string                `json:"url"`
-	Status       string                `json:"status"`
-	Code         string                `json:"code"`
-	Base         []string              `json:"base"`
+	ResourceType string                       `json:"resourceType,omitempty"`
+	Name         string                       `json:"name"`
+	Type         SearchParameterTypeV2        `json:"type"`
+	Expression   string                       `json:"expression"`
+	Description  string                       `json:"description"`
+	Url          string                       `json:"url"`
+	Status       string                       `json:"status"`
+	Code         string                       `json:"code"`
+	Base         []string                     `json:"base"`
+	Version      string                       `json:"version,omitempty"`
+	DerivedFrom  string                       `json:"derivedFrom,omitempty"`
+	Target       []string                     `json:"target,omitempty"`
+	MultipleOr   *bool                        `json:"multipleOr,omitempty"`
+	MultipleAnd  *bool                        `json:"multipleAnd,omitempty"`
+	Comparator   []string                     `json:"comparator,omitempty"`
+	Modifier     []string                     `json:"modifier,omitempty"`
+	Chain        []string                     `json:"chain,omitempty"`
+	Component    []SearchParameterComponentV2 `json:"component,omitempty"`
+}
+
+// SearchParameterComponentV2 is a part of a composite search parameter
+type SearchParameterComponentV2 struct {
+	Definition string `json:"definition"`
+	Expression string `json:"expression"`
 }
 
 func (*SearchParameterV2) GetResourcePath() string {
 	return "fhir/SearchParameter"
 }
 
-type SearchParameterTypeV2 int
+type SearchParameterTypeV2 string
 
 const (
-	SearchParameterTypeV2String SearchParameterTypeV2 = iota
-	SearchParameterTypeV2Number
-	SearchParameterTypeV2Date
-	SearchParameterTypeV2Token
-	SearchParameterTypeV2Quantity
-	SearchParameterTypeV2Reference
-	SearchParameterTypeV2Uri
-	SearchParameterTypeV2Composite
+	SearchParameterTypeV2String    SearchParameterTypeV2 = "string"
+	SearchParameterTypeV2Number    SearchParameterTypeV2 = "number"
+	SearchParameterTypeV2Date      SearchParameterTypeV2 = "date"
+	SearchParameterTypeV2Token     SearchParameterTypeV2 = "token"
+	SearchParameterTypeV2Quantity  SearchParameterTypeV2 = "quantity"
+	SearchParameterTypeV2Reference SearchParameterTypeV2 = "reference"
+	SearchParameterTypeV2Uri       SearchParameterTypeV2 = "uri"
+	SearchParameterTypeV2Composite SearchParameterTypeV2 = "composite"
 )
 
-func (t SearchParameterTypeV2) ToString() string {
-	switch t {
-	case SearchParameterTypeV2String:
-		return "string"
-	case SearchParameterTypeV2Number:
-		return "number"
-	case SearchParameterTypeV2Date:
-		return "date"
-	case SearchParameterTypeV2Token:
-		return "token"
-	case SearchParameterTypeV2Quantity:
-		return "quantity"
-	case SearchParameterTypeV2Reference:
-		return "reference"
-	case SearchParameterTypeV2Uri:
-		return "uri"
-	case SearchParameterTypeV2Composite:
-		return "composite"
-	}
-	log.Panicf("Unexpected SearchParameterTypeV2 %d\n", t)
-	return ""
+// SearchParameterTypesV2 are the search parameter types the provider supports
+var SearchParameterTypesV2 = EnumValues[SearchParameterTypeV2]{
+	SearchParameterTypeV2String,
+	SearchParameterTypeV2Number,
+	SearchParameterTypeV2Date,
+	SearchParameterTypeV2Token,
+	SearchParameterTypeV2Quantity,
+	SearchParameterTypeV2Reference,
+	SearchParameterTypeV2Uri,
+	SearchParameterTypeV2Composite,
 }
 
 const ErrInvalidSearchParameterTypeV2 AidboxError = "Unsupported search parameter type"
 
 func ParseSearchParameterTypeV2(typeString string) (SearchParameterTypeV2, error) {
-	switch typeString {
-	case "string":
-		return SearchParameterTypeV2String, nil
-	case "number":
-		return SearchParameterTypeV2Number, nil
-	case "date":
-		return SearchParameterTypeV2Date, nil
-	case "token":
-		return SearchParameterTypeV2Token, nil
-	case "quantity":
-		return SearchParameterTypeV2Quantity, nil
-	case "reference":
-		return SearchParameterTypeV2Reference, nil
-	case "uri":
-		return SearchParameterTypeV2Uri, nil
-	case "composite":
-		return SearchParameterTypeV2Composite, nil
-	default:
-		return 0, ErrInvalidSearchParameterTypeV2
-	}
-}
-
-func (t SearchParameterTypeV2) MarshalJSON() ([]byte, error) {
-	buffer := bytes.NewBufferString(`"`)
-	buffer.WriteString(t.ToString())
-	buffer.WriteString(`"`)
-	return buffer.Bytes(), nil
+	return SearchParameterTypesV2.parse(typeString, ErrInvalidSearchParameterTypeV2)
 }
 
-func (t *SearchParameterTypeV2) UnmarshalJSON(b []byte) error {
-	var j string
-	if err := json.Unmarshal(b, &j); err != nil {
-		return err
-	}
-	tt, err := ParseSearchParameterTypeV2(j)
-	if err != nil {
-		return err
-	}
-	*t = tt
-	return nil
+func (t SearchParameterTypeV2) IsKnown() bool {
+	return SearchParameterTypesV2.Contains(t)
 }
 
 func (apiClient *ApiClient) CreateSearchParameterV2(ctx context.Context, searchParameter *SearchParameterV2) (*SearchParameterV2, error) {
@@ -0,0 +1,71 @@
+package aidbox
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"golang.org/x/time/rate"
+)
+
+// throttle limits the requests in flight and their rate, shared by every resource using the client
+type throttle struct {
+	slots   chan struct{}
+	limiter *rate.Limiter
+}
+
+func newThrottle(maxConcurrentRequests int, requestsPerSecond float64) *throttle {
+	t := &throttle{}
+	if maxConcurrentRequests > 0 {
+		t.slots = make(chan struct{}, maxConcurrentRequests)
+	}
+	if requestsPerSecond > 0 {
+		// no bursts, the requests are spread evenly
+		t.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
+	}
+	return t
+}
+
+// acquire waits until the request may be sent, the returned function has to be called once it completed
+func (t *throttle) acquire(ctx context.Context) (func(), error) {
+	if t.limiter != nil {
+		err := t.limiter.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case t.slots <- struct{}{}:
+		return func() { <-t.slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// waitForTurn applies MaxConcurrentRequests and RequestsPerSecond to the request, reporting how long it had to wait
+func (apiClient *ApiClient) waitForTurn(req *http.Request) (func(), error) {
+	apiClient.throttleOnce.Do(func() {
+		apiClient.throttle = newThrottle(apiClient.MaxConcurrentRequests, apiClient.RequestsPerSecond)
+	})
+	start := time.Now()
+	release, err := apiClient.throttle.acquire(req.Context())
+	if err != nil {
+		return nil, err
+	}
+	wait := time.Since(start)
+	fields := map[string]interface{}{
+		"method": req.Method,
+		"url":    req.URL.String(),
+		"wait":   wait.String(),
+	}
+	if wait >= time.Second {
+		tflog.Info(req.Context(), "Aidbox request was throttled", fields)
+	} else if wait >= time.Millisecond {
+		tflog.Debug(req.Context(), "Aidbox request was throttled", fields)
+	}
+	return release, nil
+}
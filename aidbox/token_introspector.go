@@ -1,10 +1,7 @@
 package aidbox
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"log"
 )
 
 type TokenIntrospector struct {
@@ -29,56 +26,27 @@ type TokenIntrospectorJWT struct {
 	Secret string `json:"secret,omitempty"`
 }
 
-type TokenIntrospectorType int
+type TokenIntrospectorType string
 
 const (
-	TokenIntrospectorTypeJWT TokenIntrospectorType = iota
-	TokenIntrospectorTypeOpaque
+	TokenIntrospectorTypeJWT    TokenIntrospectorType = "jwt"
+	TokenIntrospectorTypeOpaque TokenIntrospectorType = "opaque"
 )
 
-const ErrInvalidTokenIntrospectorType AidboxError = "Invalid token introspector type"
-
-func ParseTokenIntrospectorType(typeString string) (TokenIntrospectorType, error) {
-	switch typeString {
-	case "opaque":
-		return TokenIntrospectorTypeOpaque, nil
-	case "jwt":
-		return TokenIntrospectorTypeJWT, nil
-	default:
-		return 0, ErrInvalidTokenIntrospectorType
-	}
+// TokenIntrospectorTypes are the token introspector types the provider supports
+var TokenIntrospectorTypes = EnumValues[TokenIntrospectorType]{
+	TokenIntrospectorTypeJWT,
+	TokenIntrospectorTypeOpaque,
 }
 
-func (g TokenIntrospectorType) ToString() string {
-	switch g {
-	case TokenIntrospectorTypeOpaque:
-		return "opaque"
-	case TokenIntrospectorTypeJWT:
-		return "jwt"
-	}
-	// Expect the compiler to have spotted problems before now
-	log.Panicf("Unexpected TokenIntrospectorType %d\n", g)
-	return ""
-}
+const ErrInvalidTokenIntrospectorType AidboxError = "Invalid token introspector type"
 
-func (g TokenIntrospectorType) MarshalJSON() ([]byte, error) {
-	buffer := bytes.NewBufferString(`"`)
-	buffer.WriteString(g.ToString())
-	buffer.WriteString(`"`)
-	return buffer.Bytes(), nil
+func ParseTokenIntrospectorType(typeString string) (TokenIntrospectorType, error) {
+	return TokenIntrospectorTypes.parse(typeString, ErrInvalidTokenIntrospectorType)
 }
 
-func (g *TokenIntrospectorType) UnmarshalJSON(b []byte) error {
-	var j string
-	if err := json.Unmarshal(b, &j); err != nil {
-		return err
-	}
-	tt, err := ParseTokenIntrospectorType(j)
-	if err != nil {
-		return err
-	}
-	*g = tt
-	return nil
+func (g TokenIntrospectorType) IsKnown() bool {
+	return TokenIntrospectorTypes.Contains(g)
 }
 
 func (apiClient *ApiClient) CreateTokenIntrospector(ctx context.Context, introspector *TokenIntrospector) (*TokenIntrospector, error) {
@@ -1,20 +1,62 @@
 package aidbox
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+)
 
 type UserTwoFactor struct {
 	Enabled bool `json:"enabled"`
 }
+
+type UserName struct {
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type Identifier struct {
+	System string `json:"system,omitempty"`
+	Value  string `json:"value,omitempty"`
+}
+
 type User struct {
 	ResourceBase
-	TwoFactor UserTwoFactor `json:"twoFactor"`
+	Email string `json:"email,omitempty"`
+	// Password is only sent, the server returns a hash of it
+	Password   string          `json:"password,omitempty"`
+	Name       *UserName       `json:"name,omitempty"`
+	Active     bool            `json:"active,omitempty"`
+	Data       json.RawMessage `json:"data,omitempty"`
+	FhirUser   *Reference      `json:"fhirUser,omitempty"`
+	Identifier []Identifier    `json:"identifier,omitempty"`
+	TwoFactor  *UserTwoFactor  `json:"twoFactor,omitempty"`
 }
 
 func (*User) GetResourcePath() string {
 	return "User"
 }
 
+func (apiClient *ApiClient) CreateUser(ctx context.Context, user *User) (*User, error) {
+	response := &User{}
+	return response, apiClient.createResource(ctx, user, response)
+}
+
 func (apiClient *ApiClient) GetUser(ctx context.Context, id string) (*User, error) {
 	response := &User{}
 	return response, apiClient.getResource(ctx, id, response)
 }
+
+// FindUsers searches users with search parameters, e.g. email or identifier
+func (apiClient *ApiClient) FindUsers(ctx context.Context, params url.Values) ([]*User, error) {
+	return listResources[User](ctx, apiClient, (&User{}).GetResourcePath()+"?"+params.Encode())
+}
+
+func (apiClient *ApiClient) UpdateUser(ctx context.Context, q *User) (*User, error) {
+	response := &User{}
+	return response, apiClient.updateResource(ctx, q, response)
+}
+
+func (apiClient *ApiClient) DeleteUser(ctx context.Context, id string) error {
+	return apiClient.deleteResource(ctx, id, &User{})
+}
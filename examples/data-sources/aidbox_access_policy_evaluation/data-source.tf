@@ -0,0 +1,13 @@
+data "aidbox_access_policy_evaluation" "portal_reads_patients" {
+  method    = "GET"
+  path      = "/fhir/Patient"
+  params    = { name = "ann" }
+  client_id = "portal"
+}
+
+check "portal_can_read_patients" {
+  assert {
+    condition     = data.aidbox_access_policy_evaluation.portal_reads_patients.allowed
+    error_message = "The portal can't search patients"
+  }
+}
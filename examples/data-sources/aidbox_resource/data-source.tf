@@ -0,0 +1,9 @@
+data "aidbox_resource" "bootstrapped_client" {
+  resource_type = "Client"
+  resource_id   = "root"
+  strip_meta    = true
+}
+
+output "bootstrapped_client_grant_types" {
+  value = jsondecode(data.aidbox_resource.bootstrapped_client.resource).grant_types
+}
@@ -0,0 +1,10 @@
+data "aidbox_resources" "acme_organizations" {
+  resource_type = "Organization"
+  params = {
+    name = "acme"
+  }
+}
+
+output "acme_organization_names" {
+  value = [for r in data.aidbox_resources.acme_organizations.resources : jsondecode(r).name]
+}
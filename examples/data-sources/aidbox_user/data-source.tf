@@ -1,3 +1,7 @@
 data "aidbox_user" "admin_user" {
   id = "admin"
-}
\ No newline at end of file
+}
+
+data "aidbox_user" "by_email" {
+  email = "jdoe@example.com"
+}
@@ -35,3 +35,31 @@ resource "aidbox_access_policy" "example_allow" {
     resource_type = "Client"
   }
 }
+
+resource "aidbox_access_policy" "example_sql" {
+  description = "Practitioners can read their own record"
+  engine      = "sql"
+  role_name   = "practitioner"
+  sql {
+    query = "SELECT {{uri}} = '/Practitioner/' || {{user.data.practitioner_id}}"
+  }
+}
+
+resource "aidbox_access_policy" "example_complex" {
+  description = "Signed in users can use the FHIR read operation on GET"
+  engine      = "complex"
+  and {
+    engine = "sql"
+    sql {
+      query = "SELECT {{user.id}} IS NOT NULL"
+    }
+  }
+  and {
+    engine = "matcho"
+    matcho = jsonencode({ "request-method" = "get" })
+  }
+  link {
+    resource_id   = "FhirRead"
+    resource_type = "Operation"
+  }
+}
@@ -0,0 +1,29 @@
+# The value set and the questionnaire using it are always changed together
+resource "aidbox_bundle" "colours" {
+  resources = [
+    jsonencode({
+      resourceType = "ValueSet"
+      id           = "colours"
+      status       = "active"
+      compose = {
+        include = [{
+          system = "http://example.com/colours"
+          concept = [
+            { code = "red" },
+            { code = "green" },
+          ]
+        }]
+      }
+    }),
+    jsonencode({
+      resourceType = "Questionnaire"
+      id           = "favourite-colour"
+      status       = "active"
+      item = [{
+        linkId         = "colour"
+        type           = "choice"
+        answerValueSet = "http://example.com/ValueSet/colours"
+      }]
+    }),
+  ]
+}
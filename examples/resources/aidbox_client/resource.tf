@@ -3,3 +3,22 @@ resource "aidbox_client" "example" {
   secret      = "secret"
   grant_types = ["basic"]
 }
+
+resource "aidbox_client" "smart_app" {
+  name        = "smart-app"
+  secret      = "secret"
+  grant_types = ["authorization_code", "refresh_token"]
+  auth {
+    authorization_code {
+      redirect_uri    = "https://app.example.com/callback"
+      token_format    = "jwt"
+      refresh_token   = true
+      secret_required = true
+      pkce            = true
+    }
+  }
+  smart {
+    launch_uri = "https://app.example.com/launch"
+    name       = "Example app"
+  }
+}
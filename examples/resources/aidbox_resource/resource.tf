@@ -4,4 +4,28 @@ resource "aidbox_resource" "my_aidbox_job" {
     resourceType = "AidboxJob"
     type         = "periodic"
   })
-}
\ No newline at end of file
+}
+
+# the server assigns the id of the organization, which is found by its identifier
+resource "aidbox_resource" "organization" {
+  conditional_update_query = "identifier=http://example.com/ods|ABC"
+  resource = jsonencode({
+    resourceType = "Organization"
+    name         = "Example practice"
+    identifier   = [{ system = "http://example.com/ods", value = "ABC" }]
+  })
+}
+
+resource "aidbox_resource" "practice_roles" {
+  resource_yaml = file("${path.module}/resources/practice-roles.yaml")
+}
+
+# seeded terminology survives terraform destroy
+resource "aidbox_resource" "seeded_value_set" {
+  delete_mode = "abandon"
+  resource = jsonencode({
+    resourceType = "ValueSet"
+    id           = "seeded"
+    status       = "active"
+  })
+}
@@ -0,0 +1,4 @@
+# every .json and .ndjson file under seed/, e.g. seed/value-sets/colours.json
+resource "aidbox_resource_set" "seed" {
+  path = "${path.module}/seed"
+}
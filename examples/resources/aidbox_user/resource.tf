@@ -0,0 +1,19 @@
+resource "aidbox_user" "practitioner_login" {
+  user_id             = "jdoe"
+  password            = var.jdoe_password
+  password_wo_version = 1
+  email               = "jdoe@example.com"
+  active              = true
+  name {
+    given_name  = "Jane"
+    family_name = "Doe"
+  }
+  fhir_user {
+    resource_type = "Practitioner"
+    resource_id   = "jdoe"
+  }
+  identifier {
+    system = "https://example.com/staff"
+    value  = "12345"
+  }
+}
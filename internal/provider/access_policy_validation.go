@@ -0,0 +1,101 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// matchoOperators are the operators a matcho pattern can use as keys, see
+// https://docs.aidbox.app/modules/security-and-access-control/access-policies/matcho
+var matchoOperators = []string{"$contains", "$enum", "$every", "$not", "$one-of"}
+
+func parseJsonAttribute(i interface{}, path cty.Path) (interface{}, diag.Diagnostics) {
+	var value interface{}
+	err := json.Unmarshal([]byte(i.(string)), &value)
+	if err != nil {
+		return nil, diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid JSON",
+			Detail:        err.Error(),
+			AttributePath: path,
+		}}
+	}
+	return value, nil
+}
+
+// validateAccessPolicySchema compiles the schema of the json-schema engine, which catches keywords with values of the
+// wrong type, malformed patterns and references to definitions that don't exist. Keywords aidbox doesn't know are
+// ignored by it as by the compiler. External references are left to aidbox, nothing is fetched or read during the plan.
+func validateAccessPolicySchema(i interface{}, path cty.Path) diag.Diagnostics {
+	if _, diags := parseJsonAttribute(i, path); diags.HasError() {
+		return diags
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	compiler.LoadURL = skipExternalSchema
+	err := compiler.AddResource("schema.json", strings.NewReader(i.(string)))
+	if err == nil {
+		_, err = compiler.Compile("schema.json")
+	}
+	if err != nil {
+		return diag.Diagnostics{{
+			Severity:      diag.Error,
+			Summary:       "Invalid JSON schema",
+			Detail:        err.Error(),
+			AttributePath: path,
+		}}
+	}
+	return nil
+}
+
+// skipExternalSchema stands in for the schema of an external reference with one that accepts everything
+func skipExternalSchema(string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader("{}")), nil
+}
+
+// unknownMatchoOperators finds the keys of the pattern which look like an operator but aren't one, by the JSON pointer
+// of the object they're in
+func unknownMatchoOperators(pattern interface{}, pointer string) []string {
+	var unknown []string
+	switch p := pattern.(type) {
+	case map[string]interface{}:
+		for key, value := range p {
+			if strings.HasPrefix(key, "$") && !slices.Contains(matchoOperators, key) {
+				unknown = append(unknown, fmt.Sprintf("%s at %q", key, pointer))
+			}
+			unknown = append(unknown, unknownMatchoOperators(value, pointer+"/"+key)...)
+		}
+	case []interface{}:
+		for i, value := range p {
+			unknown = append(unknown, unknownMatchoOperators(value, fmt.Sprintf("%s/%d", pointer, i))...)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// validateMatcho lints the pattern of the matcho and matcho-rpc engines. Unknown operators are only warned about, as
+// aidbox may have added some since.
+func validateMatcho(i interface{}, path cty.Path) diag.Diagnostics {
+	pattern, diags := parseJsonAttribute(i, path)
+	if diags.HasError() {
+		return diags
+	}
+	for _, unknown := range unknownMatchoOperators(pattern, "") {
+		diags = append(diags, diag.Diagnostic{
+			Severity:      diag.Warning,
+			Summary:       "Unknown matcho operator",
+			Detail:        fmt.Sprintf("%s isn't one of the matcho operators %s, so it's matched as a field", unknown, strings.Join(matchoOperators, ", ")),
+			AttributePath: path,
+		})
+	}
+	return diags
+}
@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAccessPolicySchema(t *testing.T) {
+	path := cty.GetAttrPath("schema")
+
+	assert.Empty(t, validateAccessPolicySchema(schema_v1, path))
+
+	diags := validateAccessPolicySchema(`{"properties": {"uri": {"type": "strin"}}}`, path)
+	assert.Len(t, diags, 1)
+	assert.Equal(t, "Invalid JSON schema", diags[0].Summary)
+	assert.Equal(t, path, diags[0].AttributePath)
+
+	diags = validateAccessPolicySchema(`{"required": "client"}`, path)
+	assert.Len(t, diags, 1)
+
+	assert.Empty(t, validateAccessPolicySchema(`{"definitions": {"id": {"type": "string"}}, "properties": {"client": {"properties": {"id": {"$ref": "#/definitions/id"}}}}}`, path))
+	diags = validateAccessPolicySchema(`{"properties": {"client": {"$ref": "#/definitions/missing"}}}`, path)
+	assert.Len(t, diags, 1)
+
+	// external references are neither fetched nor read
+	assert.Empty(t, validateAccessPolicySchema(`{"properties": {"user": {"$ref": "http://127.0.0.1:1/user.json"}}}`, path))
+	assert.Empty(t, validateAccessPolicySchema(`{"properties": {"user": {"$ref": "file:///nonexistent/user.json"}}}`, path))
+
+	diags = validateAccessPolicySchema(`{"properties": `, path)
+	assert.Len(t, diags, 1)
+	assert.Equal(t, "Invalid JSON", diags[0].Summary)
+}
+
+func TestValidateMatcho(t *testing.T) {
+	path := cty.GetAttrPath("matcho")
+
+	assert.Empty(t, validateMatcho(matcho, path))
+
+	diags := validateMatcho(`{"uri": {"$one_of": ["#/Patient"]}, "params": [{"$nope": 1}], "user": {"$enum": ["a"]}}`, path)
+	assert.Len(t, diags, 2)
+	assert.Equal(t, diag.Warning, diags[0].Severity)
+	assert.Contains(t, diags[0].Detail, `$nope at "/params/0"`)
+	assert.Contains(t, diags[1].Detail, `$one_of at "/uri"`)
+
+	diags = validateMatcho(`{`, path)
+	assert.True(t, diags.HasError())
+}
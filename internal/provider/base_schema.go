@@ -1,12 +1,30 @@
 package provider
 
 import (
+	"time"
+
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
 )
 
 func baseBoxResourceSchema() map[string]*schema.Schema {
-	return map[string]*schema.Schema{}
+	return map[string]*schema.Schema{
+		"version_id": {
+			Description: "Version of the resource on the server, updates fail if it was changed outside of terraform since",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"last_updated": {
+			Description: "When the resource was last changed on the server, in RFC 3339 format",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"created_at": {
+			Description: "When the resource was created on the server, in RFC 3339 format",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
 }
 
 func resourceFullSchema(resourceSchema map[string]*schema.Schema) map[string]*schema.Schema {
@@ -24,10 +42,29 @@ func mapResourceBaseToData(v *aidbox.ResourceBase, data *schema.ResourceData) {
 	if v.ID != "" {
 		data.SetId(v.ID)
 	}
+	mapResourceMetaToData(v.Meta, data)
+}
+
+// mapResourceMetaToData sets the server maintained metadata, which is left empty where the server didn't send it
+func mapResourceMetaToData(meta *aidbox.ResourceBaseMeta, data *schema.ResourceData) {
+	if meta == nil {
+		meta = &aidbox.ResourceBaseMeta{}
+	}
+	data.Set("version_id", meta.VersionId)
+	data.Set("last_updated", formatTimestamp(meta.LastUpdated))
+	data.Set("created_at", formatTimestamp(meta.CreatedAt))
+}
+
+func formatTimestamp(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339Nano)
 }
 
 func mapResourceBaseFromData(d *schema.ResourceData) aidbox.ResourceBase {
 	res := aidbox.ResourceBase{}
 	res.ID = d.Id()
+	res.VersionId = d.Get("version_id").(string)
 	return res
 }
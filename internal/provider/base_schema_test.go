@@ -0,0 +1,59 @@
+package provider
+
+import (
+	"testing"
+	"time"
+
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapResourceBaseToData(t *testing.T) {
+	t.Run("should expose the server metadata", func(t *testing.T) {
+		d := resourceAccessPolicy().Data(nil)
+		createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+		lastUpdated := time.Date(2024, 3, 2, 11, 30, 0, 123456000, time.UTC)
+
+		mapResourceBaseToData(&aidbox.ResourceBase{
+			ID:   "ankh-morpork",
+			Meta: &aidbox.ResourceBaseMeta{VersionId: "7", CreatedAt: &createdAt, LastUpdated: &lastUpdated},
+		}, d)
+
+		assert.Equal(t, "ankh-morpork", d.Id())
+		assert.Equal(t, "7", d.Get("version_id"))
+		assert.Equal(t, "2024-03-01T10:00:00Z", d.Get("created_at"))
+		assert.Equal(t, "2024-03-02T11:30:00.123456Z", d.Get("last_updated"))
+		assert.Equal(t, "7", mapResourceBaseFromData(d).VersionId)
+	})
+
+	t.Run("should leave missing timestamps empty", func(t *testing.T) {
+		d := resourceAccessPolicy().Data(nil)
+
+		mapResourceBaseToData(&aidbox.ResourceBase{ID: "ankh-morpork", Meta: &aidbox.ResourceBaseMeta{VersionId: "1"}}, d)
+
+		assert.Equal(t, "", d.Get("created_at"))
+		assert.Equal(t, "", d.Get("last_updated"))
+	})
+
+	t.Run("should clear the metadata of a previous read if there's none", func(t *testing.T) {
+		d := resourceAccessPolicy().Data(nil)
+		createdAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+		mapResourceBaseToData(&aidbox.ResourceBase{ID: "ankh-morpork", Meta: &aidbox.ResourceBaseMeta{VersionId: "1", CreatedAt: &createdAt}}, d)
+
+		mapResourceBaseToData(&aidbox.ResourceBase{ID: "ankh-morpork"}, d)
+
+		assert.Equal(t, "", d.Get("version_id"))
+		assert.Equal(t, "", d.Get("created_at"))
+	})
+	t.Run("should send the version the resource mappers got from the state", func(t *testing.T) {
+		d := resourceClient().Data(nil)
+		d.Set("name", "guild")
+		mapResourceBaseToData(&aidbox.ResourceBase{ID: "guild", Meta: &aidbox.ResourceBaseMeta{VersionId: "3"}}, d)
+
+		client, err := mapClientFromData(d)
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "guild", client.ID)
+		assert.Equal(t, "3", client.VersionId)
+	})
+}
@@ -0,0 +1,126 @@
+package provider
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+)
+
+func dataSourceAccessPolicyEvaluation() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceAccessPolicyEvaluationRead,
+		Schema:      dataSourceSchemaAccessPolicyEvaluation(),
+		Description: "Evaluates the access policies against a described request without making it, with aidbox's policy " +
+			"debugging https://docs.aidbox.app/modules/security-and-access-control/access-policies#debug-access-policies. " +
+			"Use it in `check` blocks or `terraform test` assertions to test what the policies allow.",
+	}
+}
+
+func mapAccessPolicyEvaluationFromData(d *schema.ResourceData) *aidbox.PolicyEvaluationRequest {
+	request := &aidbox.PolicyEvaluationRequest{
+		Request: aidbox.PolicyEvaluationHttpRequest{
+			Method:  strings.ToLower(d.Get("method").(string)),
+			Uri:     d.Get("path").(string),
+			Params:  toStringMap(d.Get("params")),
+			Headers: toStringMap(d.Get("headers")),
+		},
+	}
+	if user, ok := d.GetOk("user_id"); ok {
+		request.User = &aidbox.IdReference{ID: user.(string)}
+	}
+	if client, ok := d.GetOk("client_id"); ok {
+		request.Client = &aidbox.IdReference{ID: client.(string)}
+	}
+	return request
+}
+
+func dataSourceAccessPolicyEvaluationRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	request := mapAccessPolicyEvaluationFromData(d)
+	evaluation, err := apiClient.EvaluatePolicies(ctx, request)
+	if err != nil {
+		return diagFromErr(err, nil)
+	}
+	// the same request always has the same id
+	requestJson, err := json.Marshal(request)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	sum := sha256.Sum256(requestJson)
+	d.SetId(hex.EncodeToString(sum[:]))
+	d.Set("allowed", evaluation.Allowed())
+	d.Set("matched_policies", evaluation.MatchedPolicies())
+	operation := ""
+	if evaluation.Operation != nil {
+		operation = evaluation.Operation.ID
+	}
+	d.Set("operation", operation)
+	return nil
+}
+
+func dataSourceSchemaAccessPolicyEvaluation() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"method": {
+			Description:  "HTTP method of the request, e.g. GET",
+			Type:         schema.TypeString,
+			Required:     true,
+			ValidateFunc: validation.StringInSlice([]string{"get", "post", "put", "patch", "delete", "head", "options"}, true),
+		},
+		"path": {
+			Description: "Path of the request, e.g. /fhir/Patient/pt-1",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"params": {
+			Description: "Query parameters of the request",
+			Type:        schema.TypeMap,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"headers": {
+			Description: "Headers of the request, with lower case names",
+			Type:        schema.TypeMap,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"user_id": {
+			Description: "ID of the user making the request",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"client_id": {
+			Description: "ID of the client making the request",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"allowed": {
+			Description: "Whether any of the access policies grants the request",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
+		"matched_policies": {
+			Description: "IDs of the access policies granting the request, in alphabetical order",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"operation": {
+			Description: "ID of the operation the request was routed to, e.g. FhirRead",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+}
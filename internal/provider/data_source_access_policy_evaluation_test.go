@@ -0,0 +1,87 @@
+package provider
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccDataSourceAccessPolicyEvaluation(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceAccessPolicyEvaluation,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.aidbox_access_policy_evaluation.read", "allowed", "true"),
+					resource.TestCheckResourceAttr("data.aidbox_access_policy_evaluation.read", "matched_policies.#", "1"),
+					resource.TestCheckResourceAttrPair("data.aidbox_access_policy_evaluation.read", "matched_policies.0", "aidbox_access_policy.get_only", "id"),
+					resource.TestCheckResourceAttr("data.aidbox_access_policy_evaluation.delete", "allowed", "false"),
+					resource.TestCheckResourceAttr("data.aidbox_access_policy_evaluation.delete", "matched_policies.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataSourceAccessPolicyEvaluation = `
+resource "aidbox_client" "evaluation" {
+  name        = "evaluation-client"
+  secret      = "__sha256:2BB80D537B1DA3E38BD30361AA855686BDE0EACD7162FEF6A25FE97BF527A25B"
+  grant_types = ["basic"]
+}
+
+resource "aidbox_access_policy" "get_only" {
+  description = "The evaluation client can only read"
+  engine      = "matcho"
+  matcho = jsonencode({
+    "client"         = { "id" = aidbox_client.evaluation.name }
+    "request-method" = "get"
+  })
+}
+
+data "aidbox_access_policy_evaluation" "read" {
+  method     = "GET"
+  path       = "/fhir/Patient"
+  client_id  = aidbox_client.evaluation.name
+  depends_on = [aidbox_access_policy.get_only]
+}
+
+data "aidbox_access_policy_evaluation" "delete" {
+  method     = "DELETE"
+  path       = "/fhir/Patient/pt-1"
+  client_id  = aidbox_client.evaluation.name
+  depends_on = [aidbox_access_policy.get_only]
+}
+`
+
+func TestDataSourceAccessPolicyEvaluationRead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"request": {"request-method": "delete", "uri": "/fhir/Patient/pt-1"}, "client": {"id": "portal"}}`, string(body))
+		w.Write([]byte(`{"operation": {"id": "FhirDelete"}, "policies": {"portal-read": {"eval-result": false}}}`))
+	}))
+	defer server.Close()
+	apiClient := aidbox.NewApiClient(server.URL, "foo", "bar")
+	d := schema.TestResourceDataRaw(t, dataSourceSchemaAccessPolicyEvaluation(), map[string]interface{}{
+		"method":    "DELETE",
+		"path":      "/fhir/Patient/pt-1",
+		"client_id": "portal",
+	})
+
+	diags := dataSourceAccessPolicyEvaluationRead(context.TODO(), d, apiClient)
+
+	assert.Empty(t, diags)
+	assert.NotEmpty(t, d.Id())
+	assert.Equal(t, false, d.Get("allowed"))
+	assert.Equal(t, []interface{}{}, d.Get("matched_policies"))
+	assert.Equal(t, "FhirDelete", d.Get("operation"))
+}
@@ -0,0 +1,74 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+)
+
+func dataSourceAidboxResource() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceAidboxResourceRead,
+		Schema:      dataSourceSchemaAidboxResource(),
+		Description: "Any aidbox resource by its type and ID, e.g. one created outside of terraform. " +
+			"Read-only counterpart of the aidbox_resource resource.",
+	}
+}
+
+func dataSourceAidboxResourceRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	resourceTypeAndId := d.Get("resource_type").(string) + "/" + d.Get("resource_id").(string)
+	res, err := apiClient.GetGenericResource(ctx, resourceTypeAndId)
+	if err != nil {
+		if errors.Is(err, aidbox.NotFoundError) {
+			return diag.Errorf("resource %s does not exist", resourceTypeAndId)
+		}
+		return diagFromErr(err, nil)
+	}
+	content := res.ResourceContent
+	if d.Get("strip_meta").(bool) {
+		var h map[string]any
+		err = json.Unmarshal(content, &h)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		delete(h, "meta")
+		content, err = json.Marshal(h)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+	}
+	d.SetId(res.ResourceTypeAndId)
+	d.Set("resource", string(content))
+	return nil
+}
+
+func dataSourceSchemaAidboxResource() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"resource_type": {
+			Description: "The type of the resource, e.g. Client",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"resource_id": {
+			Description: "The ID of the resource",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"strip_meta": {
+			Description: "Leave out the server maintained `meta` of the resource, e.g. to compare it with another one",
+			Type:        schema.TypeBool,
+			Optional:    true,
+			Default:     false,
+		},
+		"resource": {
+			Description: "Aidbox resource content in JSON format. Use `jsondecode` to access its fields.",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+	}
+}
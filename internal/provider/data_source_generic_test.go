@@ -0,0 +1,64 @@
+package provider
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceAidboxResource(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceAidboxResource,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.aidbox_resource.watch", "id", "Organization/city-watch"),
+					resource.TestCheckResourceAttrWith("data.aidbox_resource.watch", "resource", compIgnoreJsonDiff(`{
+						"resourceType": "Organization",
+						"id": "city-watch",
+						"name": "Ankh-Morpork City Watch"
+					}`)),
+					resource.TestCheckResourceAttr("data.aidbox_resource.watch_with_meta", "id", "Organization/city-watch"),
+					resource.TestMatchResourceAttr("data.aidbox_resource.watch_with_meta", "resource", regexp.MustCompile(`"meta"`)),
+				),
+			},
+			{
+				Config:      testAccDataSourceAidboxResource_missing,
+				ExpectError: regexp.MustCompile("resource Organization/unseen-university does not exist"),
+			},
+		},
+	})
+}
+
+const testAccDataSourceAidboxResource = `
+resource "aidbox_resource" "watch" {
+  resource = jsonencode({
+    resourceType = "Organization"
+    id           = "city-watch"
+    name         = "Ankh-Morpork City Watch"
+  })
+}
+
+data "aidbox_resource" "watch" {
+  resource_type = "Organization"
+  resource_id   = "city-watch"
+  strip_meta    = true
+  depends_on    = [aidbox_resource.watch]
+}
+
+data "aidbox_resource" "watch_with_meta" {
+  resource_type = "Organization"
+  resource_id   = "city-watch"
+  depends_on    = [aidbox_resource.watch]
+}
+`
+
+const testAccDataSourceAidboxResource_missing = `
+data "aidbox_resource" "missing" {
+  resource_type = "Organization"
+  resource_id   = "unseen-university"
+}
+`
@@ -0,0 +1,82 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+)
+
+func dataSourceAidboxResources() *schema.Resource {
+	return &schema.Resource{
+		ReadContext: dataSourceAidboxResourcesRead,
+		Schema:      dataSourceSchemaAidboxResources(),
+		Description: "Resources of a type matching FHIR search parameters https://docs.aidbox.app/api-1/fhir-api/search-1. " +
+			"Every page of the search result is read, use `_count` to limit the size of pages.",
+	}
+}
+
+func dataSourceAidboxResourcesRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	resourceType := d.Get("resource_type").(string)
+	params := url.Values{}
+	for k, v := range d.Get("params").(map[string]interface{}) {
+		params.Set(k, v.(string))
+	}
+	resources, err := apiClient.SearchResources(ctx, resourceType, params)
+	if err != nil {
+		return diagFromErr(err, nil)
+	}
+	ids := []string{}
+	contents := []string{}
+	for _, resource := range resources {
+		var base aidbox.ResourceBase
+		err := json.Unmarshal(resource, &base)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		ids = append(ids, base.ID)
+		contents = append(contents, string(resource))
+	}
+	d.SetId(resourceType + "?" + params.Encode())
+	d.Set("ids", ids)
+	d.Set("resources", contents)
+	return nil
+}
+
+func dataSourceSchemaAidboxResources() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"resource_type": {
+			Description: "The type of resources to search for, e.g. Organization",
+			Type:        schema.TypeString,
+			Required:    true,
+		},
+		"params": {
+			Description: "FHIR search parameters, e.g. `{ name = \"acme\" }`. Separate values with commas to match any of them.",
+			Type:        schema.TypeMap,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"ids": {
+			Description: "The IDs of the matching resources",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"resources": {
+			Description: "The matching resources in JSON format, in the same order as `ids`. Use `jsondecode` to access their fields.",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+	}
+}
@@ -0,0 +1,62 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+)
+
+func TestAccDataSourceAidboxResources(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccDataSourceAidboxResources,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.aidbox_resources.guilds", "ids.#", "2"),
+					resource.TestCheckResourceAttr("data.aidbox_resources.guilds", "ids.0", "guild-of-assassins"),
+					resource.TestCheckResourceAttr("data.aidbox_resources.guilds", "ids.1", "guild-of-thieves"),
+					resource.TestCheckResourceAttr("data.aidbox_resources.guilds", "resources.#", "2"),
+					resource.TestCheckResourceAttr("data.aidbox_resources.nobody", "ids.#", "0"),
+				),
+			},
+		},
+	})
+}
+
+const testAccDataSourceAidboxResources = `
+resource "aidbox_resource" "guild_of_assassins" {
+  resource = jsonencode({
+    resourceType = "Organization"
+    id           = "guild-of-assassins"
+    name         = "Ankh-Morpork Guild of Assassins"
+  })
+}
+
+resource "aidbox_resource" "guild_of_thieves" {
+  resource = jsonencode({
+    resourceType = "Organization"
+    id           = "guild-of-thieves"
+    name         = "Ankh-Morpork Guild of Thieves"
+  })
+}
+
+data "aidbox_resources" "guilds" {
+  resource_type = "Organization"
+  params = {
+    name   = "Ankh-Morpork Guild"
+    _sort  = "_id"
+    _count = "1"
+  }
+  depends_on = [aidbox_resource.guild_of_assassins, aidbox_resource.guild_of_thieves]
+}
+
+data "aidbox_resources" "nobody" {
+  resource_type = "Organization"
+  params = {
+    name = "Unseen University"
+  }
+  depends_on = [aidbox_resource.guild_of_assassins, aidbox_resource.guild_of_thieves]
+}
+`
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"context"
+	"net/url"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
@@ -12,39 +13,137 @@ func dataSourceUser() *schema.Resource {
 	return &schema.Resource{
 		ReadContext: dataSourceUserRead,
 		Schema:      resourceFullSchema(dataSourceSchemaUser()),
-		Description: "User https://docs.aidbox.app/modules/security-and-access-control/readme-1/overview#user",
+		Description: "User https://docs.aidbox.app/modules/security-and-access-control/readme-1/overview#user. " +
+			"Looked up by ID, email or identifier, the lookup has to match exactly one user.",
 	}
 }
 
 func dataSourceUserRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
-	res, err := apiClient.GetUser(ctx, d.Get("id").(string))
-	if err != nil {
-		if handleNotFoundError(err, d) {
-			return nil
+	if id, ok := d.GetOk("id"); ok {
+		res, err := apiClient.GetUser(ctx, id.(string))
+		if err != nil {
+			if handleNotFoundError(err, d) {
+				return nil
+			}
+			return diagFromErr(err, nil)
 		}
-		return diag.FromErr(err)
+		mapUserToData(res, d)
+		return nil
 	}
-	mapUserToData(res, d)
-	return nil
-}
 
-func mapUserToData(res *aidbox.User, data *schema.ResourceData) {
-	data.SetId(res.ID)
-	data.Set("two_factor_enabled", res.TwoFactor.Enabled)
+	params := url.Values{}
+	if email, ok := d.GetOk("email"); ok {
+		params.Set("email", email.(string))
+	} else {
+		identifier := d.Get("identifier_value").(string)
+		if system, ok := d.GetOk("identifier_system"); ok {
+			identifier = system.(string) + "|" + identifier
+		}
+		params.Set("identifier", identifier)
+	}
+	users, err := apiClient.FindUsers(ctx, params)
+	if err != nil {
+		return diagFromErr(err, nil)
+	}
+	if len(users) != 1 {
+		return diag.Errorf("expected exactly one user matching %s but found %d", params.Encode(), len(users))
+	}
+	mapUserToData(users[0], d)
+	return nil
 }
 
 func dataSourceSchemaUser() map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		"id": {
-			Description: "the ID of the user in aidbox server",
+			Description:  "the ID of the user in aidbox server",
+			Type:         schema.TypeString,
+			Optional:     true,
+			Computed:     true,
+			ExactlyOneOf: []string{"id", "email", "identifier_value"},
+		},
+		"email": {
+			Description: "Email address of the user",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Computed:    true,
+		},
+		"identifier_system": {
+			Description:  "System of the identifier to look the user up by, any system if not set",
+			Type:         schema.TypeString,
+			Optional:     true,
+			RequiredWith: []string{"identifier_value"},
+		},
+		"identifier_value": {
+			Description: "Value of the identifier to look the user up by",
 			Type:        schema.TypeString,
-			Required:    true,
+			Optional:    true,
 		},
 		"two_factor_enabled": {
 			Description: "if 2FA is enabled for the user",
 			Type:        schema.TypeBool,
 			Optional:    true,
 		},
+		"active": {
+			Description: "Whether the user is active",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
+		"name": {
+			Description: "Name of the user",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"given_name": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
+					"family_name": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
+				},
+			},
+		},
+		"data": {
+			Description: "Arbitrary data about the user in JSON format",
+			Type:        schema.TypeString,
+			Computed:    true,
+		},
+		"fhir_user": {
+			Description: "The FHIR resource representing the user",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"resource_type": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
+					"resource_id": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
+				},
+			},
+		},
+		"identifier": {
+			Description: "Business identifiers of the user",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"system": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
+					"value": {
+						Type:     schema.TypeString,
+						Computed: true,
+					},
+				},
+			},
+		},
 	}
 }
@@ -0,0 +1,296 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// generatedResource is a resource read from the server, mapped onto the schema of its terraform resource type
+type generatedResource struct {
+	importId string
+	data     *schema.ResourceData
+	// warnings of the mapping, e.g. about values the provider doesn't know, are written out as comments
+	warnings diag.Diagnostics
+}
+
+// generator lists the resources of an aidbox type and maps them onto the terraform resource type modelling it
+type generator struct {
+	terraformType string
+	list          func(ctx context.Context, apiClient *aidbox.ApiClient, resource *schema.Resource) ([]generatedResource, error)
+}
+
+var generators = map[string]generator{
+	"AccessPolicy": {
+		terraformType: "aidbox_access_policy",
+		list: func(ctx context.Context, apiClient *aidbox.ApiClient, resource *schema.Resource) ([]generatedResource, error) {
+			policies, err := apiClient.ListAccessPolicies(ctx)
+			if err != nil {
+				return nil, err
+			}
+			var generated []generatedResource
+			for _, policy := range policies {
+				d := resource.Data(nil)
+				diags := mapAccessPolicyToData(policy, d)
+				if err := errorFromDiagnostics(diags); err != nil {
+					return nil, fmt.Errorf("%s: %w", policy.ID, err)
+				}
+				generated = append(generated, generatedResource{policy.ID, d, diags})
+			}
+			return generated, nil
+		},
+	},
+	"Client": {
+		terraformType: "aidbox_client",
+		list: func(ctx context.Context, apiClient *aidbox.ApiClient, resource *schema.Resource) ([]generatedResource, error) {
+			clients, err := apiClient.ListClients(ctx)
+			if err != nil {
+				return nil, err
+			}
+			var generated []generatedResource
+			for _, client := range clients {
+				d := resource.Data(nil)
+				diags := mapClientToData(client, d)
+				if err := errorFromDiagnostics(diags); err != nil {
+					return nil, fmt.Errorf("%s: %w", client.ID, err)
+				}
+				generated = append(generated, generatedResource{client.ID, d, diags})
+			}
+			return generated, nil
+		},
+	},
+	"SearchParameter": {
+		terraformType: "aidbox_search_parameter",
+		list: func(ctx context.Context, apiClient *aidbox.ApiClient, resource *schema.Resource) ([]generatedResource, error) {
+			searchParameters, err := apiClient.ListSearchParameters(ctx)
+			if err != nil {
+				return nil, err
+			}
+			var generated []generatedResource
+			for _, searchParameter := range searchParameters {
+				d := resource.Data(nil)
+				diags := mapSearchParameterToData(searchParameter, d)
+				if err := errorFromDiagnostics(diags); err != nil {
+					return nil, fmt.Errorf("%s: %w", searchParameter.ID, err)
+				}
+				generated = append(generated, generatedResource{searchParameter.ID, d, diags})
+			}
+			return generated, nil
+		},
+	},
+}
+
+// genericGenerator falls back to aidbox_resource for types the provider doesn't model
+func genericGenerator(resourceType string) generator {
+	return generator{
+		terraformType: "aidbox_resource",
+		list: func(ctx context.Context, apiClient *aidbox.ApiClient, resource *schema.Resource) ([]generatedResource, error) {
+			resources, err := apiClient.ListGenericResources(ctx, resourceType)
+			if err != nil {
+				return nil, err
+			}
+			var generated []generatedResource
+			for _, res := range resources {
+				d := resource.Data(nil)
+				// the content is written out the way importing reads it, without the id, so that the plan is empty
+				err = mapAidboxResourceToData(res, d)
+				if err != nil {
+					return nil, err
+				}
+				generated = append(generated, generatedResource{res.ResourceTypeAndId, d, nil})
+			}
+			return generated, nil
+		},
+	}
+}
+
+// Generate writes terraform configuration for every resource of the given aidbox types on the server into outputDir,
+// together with terraform 1.5+ import blocks bringing them under terraform's control. There's one file per aidbox
+// type. Sensitive attributes such as client secrets are not written out, they are declared as variables instead.
+func Generate(ctx context.Context, apiClient *aidbox.ApiClient, resourceTypes []string, outputDir string) error {
+	resources := New(apiClient)().ResourcesMap
+	labels := map[string]bool{}
+	variables := hclwrite.NewEmptyFile()
+	for _, resourceType := range resourceTypes {
+		g, ok := generators[resourceType]
+		if !ok {
+			g = genericGenerator(resourceType)
+		}
+		resource := resources[g.terraformType]
+		generated, err := g.list(ctx, apiClient, resource)
+		if err != nil {
+			return fmt.Errorf("listing %s: %w", resourceType, err)
+		}
+		file := hclwrite.NewEmptyFile()
+		for _, r := range generated {
+			label := uniqueLabel(r.importId, labels)
+			writeWarnings(file.Body(), r.warnings)
+			block := file.Body().AppendNewBlock("resource", []string{g.terraformType, label})
+			writeAttributes(block.Body(), resource.Schema, r.data.Get, label, variables.Body())
+			file.Body().AppendNewline()
+
+			importBlock := file.Body().AppendNewBlock("import", nil)
+			importBlock.Body().SetAttributeTraversal("to", hcl.Traversal{
+				hcl.TraverseRoot{Name: g.terraformType},
+				hcl.TraverseAttr{Name: label},
+			})
+			importBlock.Body().SetAttributeValue("id", cty.StringVal(r.importId))
+			file.Body().AppendNewline()
+		}
+		err = os.WriteFile(filepath.Join(outputDir, resourceType+".tf"), hclwrite.Format(file.Bytes()), 0644)
+		if err != nil {
+			return err
+		}
+	}
+	if len(variables.Body().Blocks()) > 0 {
+		return os.WriteFile(filepath.Join(outputDir, "variables.tf"), hclwrite.Format(variables.Bytes()), 0644)
+	}
+	return nil
+}
+
+// writeWarnings comments the resource that follows with the warnings about it
+func writeWarnings(body *hclwrite.Body, warnings diag.Diagnostics) {
+	for _, w := range warnings {
+		comment := "# Warning: " + w.Summary
+		if path := attributePathString(w.AttributePath); path != "" {
+			comment += " in " + path
+		}
+		if w.Detail != "" {
+			comment += ": " + w.Detail
+		}
+		body.AppendUnstructuredTokens(hclwrite.Tokens{{Type: hclsyntax.TokenComment, Bytes: []byte(comment + "\n")}})
+	}
+}
+
+var invalidLabelCharacters = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// uniqueLabel turns the id into a valid terraform resource name, unique among the generated ones
+func uniqueLabel(id string, labels map[string]bool) string {
+	label := invalidLabelCharacters.ReplaceAllString(id, "_")
+	if label == "" || !(label[0] == '_' || (label[0] >= 'a' && label[0] <= 'z') || (label[0] >= 'A' && label[0] <= 'Z')) {
+		label = "_" + label
+	}
+	unique := label
+	for i := 2; labels[unique]; i++ {
+		unique = fmt.Sprintf("%s_%d", label, i)
+	}
+	labels[unique] = true
+	return unique
+}
+
+// writeAttributes writes the configurable attributes with a value into the body, leaving out the ones that are empty
+// or only computed. Sensitive ones refer to a variable declared in variables.
+func writeAttributes(body *hclwrite.Body, resourceSchema map[string]*schema.Schema, get func(string) interface{}, label string, variables *hclwrite.Body) {
+	keys := make([]string, 0, len(resourceSchema))
+	for k := range resourceSchema {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := resourceSchema[k]
+		value := get(k)
+		if (s.Computed && !s.Optional && !s.Required) || isEmptyValue(value) {
+			continue
+		}
+		if s.Sensitive {
+			variable := label + "_" + k
+			variableBlock := variables.AppendNewBlock("variable", []string{variable})
+			variableBlock.Body().SetAttributeTraversal("type", hcl.Traversal{hcl.TraverseRoot{Name: "string"}})
+			variableBlock.Body().SetAttributeValue("sensitive", cty.True)
+			body.SetAttributeTraversal(k, hcl.Traversal{hcl.TraverseRoot{Name: "var"}, hcl.TraverseAttr{Name: variable}})
+			continue
+		}
+		if nested, ok := s.Elem.(*schema.Resource); ok {
+			for _, item := range value.([]interface{}) {
+				itemValues := item.(map[string]interface{})
+				block := body.AppendNewBlock(k, nil)
+				writeAttributes(block.Body(), nested.Schema, func(key string) interface{} { return itemValues[key] }, label, variables)
+			}
+			continue
+		}
+		if str, ok := value.(string); ok && s.DiffSuppressFunc != nil && json.Valid([]byte(str)) {
+			body.SetAttributeRaw(k, heredocTokens(str))
+			continue
+		}
+		body.SetAttributeValue(k, toCtyValue(s, value))
+	}
+}
+
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case bool:
+		return !v
+	case int:
+		return v == 0
+	case float64:
+		return v == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case *schema.Set:
+		return v.Len() == 0
+	}
+	return false
+}
+
+func toCtyValue(s *schema.Schema, value interface{}) cty.Value {
+	switch v := value.(type) {
+	case string:
+		return cty.StringVal(v)
+	case bool:
+		return cty.BoolVal(v)
+	case int:
+		return cty.NumberIntVal(int64(v))
+	case float64:
+		return cty.NumberFloatVal(v)
+	case *schema.Set:
+		return toCtyValue(s, v.List())
+	case []interface{}:
+		elemSchema, _ := s.Elem.(*schema.Schema)
+		values := make([]cty.Value, 0, len(v))
+		for _, item := range v {
+			values = append(values, toCtyValue(elemSchema, item))
+		}
+		return cty.TupleVal(values)
+	case map[string]interface{}:
+		elemSchema, _ := s.Elem.(*schema.Schema)
+		values := make(map[string]cty.Value, len(v))
+		for key, item := range v {
+			values[key] = toCtyValue(elemSchema, item)
+		}
+		return cty.ObjectVal(values)
+	}
+	panic(fmt.Sprintf("unexpected value %v of type %T", value, value))
+}
+
+// heredocTokens renders JSON as an indented heredoc, which reads a lot better than an escaped string
+func heredocTokens(jsonString string) hclwrite.Tokens {
+	var pretty bytes.Buffer
+	json.Indent(&pretty, []byte(jsonString), "", "  ")
+	// a heredoc is a template, escape anything that would be interpreted as one
+	content := strings.NewReplacer("${", "$${", "%{", "%%{").Replace(pretty.String())
+	return hclwrite.Tokens{
+		{Type: hclsyntax.TokenOHeredoc, Bytes: []byte("<<-EOT\n")},
+		{Type: hclsyntax.TokenStringLit, Bytes: []byte(content + "\n")},
+		{Type: hclsyntax.TokenCHeredoc, Bytes: []byte("EOT")},
+	}
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/Client":
+			w.Write([]byte(`{"entry": [{"resource": {"resourceType": "Client", "id": "the-luggage", "secret": "sapient pearwood", "grant_types": ["basic"]}}]}`))
+		case "/AccessPolicy":
+			w.Write([]byte(`{"entry": [{"resource": {"resourceType": "AccessPolicy", "id": "librarian", "engine": "prolog"}}]}`))
+		case "/Organization":
+			w.Write([]byte(`{"entry": [
+				{"resource": {"resourceType": "Organization", "id": "unseen-university", "name": "Unseen ${University}", "meta": {"versionId": "1"}}},
+				{"resource": {"resourceType": "Organization", "id": "unseen.university", "name": "Unseen University"}}
+			]}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+	apiClient := aidbox.NewApiClient(server.URL, "root", "secret")
+	outputDir := t.TempDir()
+	read := func(name string) string {
+		b, err := os.ReadFile(filepath.Join(outputDir, name))
+		assert.NoError(t, err)
+		return string(b)
+	}
+
+	err := Generate(context.TODO(), apiClient, []string{"AccessPolicy", "Client", "Organization"}, outputDir)
+
+	assert.NoError(t, err)
+	assert.Contains(t, read("AccessPolicy.tf"), `# Warning: Unknown value "prolog" in engine: aidbox responded with "prolog", which this version of the provider doesn't know
+resource "aidbox_access_policy" "librarian" {
+`)
+	assert.Equal(t, `resource "aidbox_client" "the-luggage" {
+  grant_types = ["basic"]
+  name        = "the-luggage"
+  secret      = var.the-luggage_secret
+}
+
+import {
+  to = aidbox_client.the-luggage
+  id = "the-luggage"
+}
+
+`, read("Client.tf"))
+	assert.Equal(t, `resource "aidbox_resource" "Organization_unseen-university" {
+  resource = <<-EOT
+{
+  "name": "Unseen $${University}",
+  "resourceType": "Organization"
+}
+EOT
+}
+
+import {
+  to = aidbox_resource.Organization_unseen-university
+  id = "Organization/unseen-university"
+}
+
+resource "aidbox_resource" "Organization_unseen_university" {
+  resource = <<-EOT
+{
+  "name": "Unseen University",
+  "resourceType": "Organization"
+}
+EOT
+}
+
+import {
+  to = aidbox_resource.Organization_unseen_university
+  id = "Organization/unseen.university"
+}
+
+`, read("Organization.tf"))
+	assert.Equal(t, `variable "the-luggage_secret" {
+  type      = string
+  sensitive = true
+}
+`, read("variables.tf"))
+}
+
+func TestUniqueLabel(t *testing.T) {
+	labels := map[string]bool{}
+	assert.Equal(t, "Patient_1", uniqueLabel("Patient/1", labels))
+	assert.Equal(t, "Patient_1_2", uniqueLabel("Patient.1", labels))
+	assert.Equal(t, "_42", uniqueLabel("42", labels))
+}
@@ -0,0 +1,123 @@
+package provider
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// parseJsonPath splits a JSON pointer such as /text/div, or a simple path such as text.div, into its segments. A *
+// segment stands for every element of an array or every field of an object.
+func parseJsonPath(path string) ([]string, error) {
+	if path == "" || path == "/" {
+		return nil, fmt.Errorf("path %q doesn't point into the resource", path)
+	}
+	if strings.HasPrefix(path, "/") {
+		segments := strings.Split(path[1:], "/")
+		for i, segment := range segments {
+			segments[i] = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+		}
+		return segments, nil
+	}
+	segments := strings.Split(path, ".")
+	for _, segment := range segments {
+		if segment == "" {
+			return nil, fmt.Errorf("path %q has an empty segment", path)
+		}
+	}
+	return segments, nil
+}
+
+func validateJsonPath(v interface{}, k string) ([]string, []error) {
+	_, err := parseJsonPath(v.(string))
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", k, err)}
+	}
+	return nil, nil
+}
+
+// removeJsonPath deletes whatever the path points at in the parsed JSON value, if anything
+func removeJsonPath(value interface{}, segments []string) {
+	if len(segments) == 0 {
+		return
+	}
+	segment, rest := segments[0], segments[1:]
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if segment != "*" && segment != key {
+				continue
+			}
+			if len(rest) == 0 {
+				delete(v, key)
+			} else {
+				removeJsonPath(field, rest)
+			}
+		}
+	case []interface{}:
+		// removing elements would shift the others, so only what's inside them can be removed
+		if len(rest) == 0 {
+			return
+		}
+		for i, element := range v {
+			if segment == "*" || segment == strconv.Itoa(i) {
+				removeJsonPath(element, rest)
+			}
+		}
+	}
+}
+
+// removeJsonPaths deletes every path from the parsed JSON value
+func removeJsonPaths(value interface{}, paths []string) error {
+	for _, path := range paths {
+		segments, err := parseJsonPath(path)
+		if err != nil {
+			return err
+		}
+		removeJsonPath(value, segments)
+	}
+	return nil
+}
+
+// jsonSubset keeps only the parts of value that are also in the pattern, recursing into objects and into arrays of the
+// same length. Arrays of different lengths are kept as they are, so that they show up as different.
+func jsonSubset(value interface{}, pattern interface{}) interface{} {
+	switch p := pattern.(type) {
+	case map[string]interface{}:
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		subset := map[string]interface{}{}
+		for key, field := range v {
+			if patternField, ok := p[key]; ok {
+				subset[key] = jsonSubset(field, patternField)
+			}
+		}
+		return subset
+	case []interface{}:
+		v, ok := value.([]interface{})
+		if !ok || len(v) != len(p) {
+			return value
+		}
+		subset := make([]interface{}, len(v))
+		for i := range v {
+			subset[i] = jsonSubset(v[i], p[i])
+		}
+		return subset
+	}
+	return value
+}
+
+// jsonMatches tells whether the actual JSON value matches the expected one, ignoring the paths in both, and anything
+// not in expected if subset is true
+func jsonMatches(actual interface{}, expected interface{}, ignorePaths []string, subset bool) bool {
+	if removeJsonPaths(actual, ignorePaths) != nil || removeJsonPaths(expected, ignorePaths) != nil {
+		return false
+	}
+	if subset {
+		actual = jsonSubset(actual, expected)
+	}
+	return reflect.DeepEqual(actual, expected)
+}
@@ -0,0 +1,98 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseJson(t *testing.T, s string) interface{} {
+	var v interface{}
+	err := json.Unmarshal([]byte(s), &v)
+	assert.Equal(t, nil, err)
+	return v
+}
+
+func TestParseJsonPath(t *testing.T) {
+	segments, err := parseJsonPath("/text/div")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"text", "div"}, segments)
+
+	segments, err = parseJsonPath("/extension/0/url~1path~0")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"extension", "0", "url/path~"}, segments)
+
+	segments, err = parseJsonPath("identifier.*.use")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"identifier", "*", "use"}, segments)
+
+	_, err = parseJsonPath("")
+	assert.Error(t, err)
+	_, err = parseJsonPath("text..div")
+	assert.Error(t, err)
+}
+
+func TestRemoveJsonPaths(t *testing.T) {
+	t.Run("should remove fields, fields of array elements and wildcards", func(t *testing.T) {
+		value := parseJson(t, `{"status": "active", "text": {"status": "generated", "div": "<div/>"},
+			"identifier": [{"use": "official", "value": "1"}, {"use": "usual", "value": "2"}],
+			"extension": [{"url": "a"}, {"url": "b"}]}`)
+
+		err := removeJsonPaths(value, []string{"status", "/text/div", "identifier.*.use", "extension.1.url", "missing.field"})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, parseJson(t, `{"text": {"status": "generated"},
+			"identifier": [{"value": "1"}, {"value": "2"}],
+			"extension": [{"url": "a"}, {}]}`), value)
+	})
+
+	t.Run("should leave array elements in place", func(t *testing.T) {
+		value := parseJson(t, `{"extension": [{"url": "a"}, {"url": "b"}]}`)
+
+		err := removeJsonPaths(value, []string{"extension.0"})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, parseJson(t, `{"extension": [{"url": "a"}, {"url": "b"}]}`), value)
+	})
+}
+
+func TestJsonMatches(t *testing.T) {
+	server := `{"resourceType": "Organization", "name": "Guild", "active": true,
+		"text": {"div": "<div>Guild</div>"}, "identifier": [{"system": "ods", "value": "A", "use": "official"}]}`
+
+	t.Run("should ignore the paths", func(t *testing.T) {
+		assert.True(t, jsonMatches(parseJson(t, server),
+			parseJson(t, `{"resourceType": "Organization", "name": "Guild", "active": true, "identifier": [{"system": "ods", "value": "A"}]}`),
+			[]string{"text", "identifier.*.use"}, false))
+		assert.False(t, jsonMatches(parseJson(t, server),
+			parseJson(t, `{"resourceType": "Organization", "name": "Guild", "identifier": [{"system": "ods", "value": "A"}]}`),
+			[]string{"text", "identifier.*.use"}, false))
+	})
+
+	t.Run("should only compare what's expected in subset mode", func(t *testing.T) {
+		assert.True(t, jsonMatches(parseJson(t, server),
+			parseJson(t, `{"resourceType": "Organization", "name": "Guild", "identifier": [{"value": "A"}]}`),
+			nil, true))
+		assert.False(t, jsonMatches(parseJson(t, server),
+			parseJson(t, `{"resourceType": "Organization", "name": "Guild", "identifier": [{"value": "B"}]}`),
+			nil, true))
+		assert.False(t, jsonMatches(parseJson(t, server),
+			parseJson(t, `{"resourceType": "Organization", "alias": ["Guild"]}`),
+			nil, true))
+		// an array with another length is different even if its elements match
+		assert.False(t, jsonMatches(parseJson(t, server),
+			parseJson(t, `{"identifier": [{"value": "A"}, {"value": "B"}]}`),
+			nil, true))
+	})
+}
+
+func TestPruneAidboxResourceContent(t *testing.T) {
+	pruned, err := pruneAidboxResourceContent(
+		`{"resourceType": "Organization", "name": "Guild", "active": true, "text": {"div": "<div/>"}}`,
+		`{"resourceType": "Organization", "name": "Old name", "text": {"div": "<div/>"}}`,
+		[]string{"/text"}, true)
+
+	assert.Equal(t, nil, err)
+	assert.JSONEq(t, `{"resourceType": "Organization", "name": "Guild"}`, pruned)
+}
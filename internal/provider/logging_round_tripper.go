@@ -1,41 +1,176 @@
 package provider
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
-	"net/http/httputil"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 )
 
-// LoggingRoundTripper is an http.RoundTripper that logs the request and response
+// httpLogSubsystem is the tflog subsystem of the HTTP traces, its level can be set on its own with
+// TF_LOG_PROVIDER_AIDBOX_HTTP
+const httpLogSubsystem = "http"
+
+const redacted = "***"
+
+// sensitiveHeaders are masked in the logs, compared in canonical form
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// sensitiveFields are the JSON keys masked in the logged bodies, compared in lower case with dashes as underscores
+var sensitiveFields = map[string]bool{
+	"secret":        true,
+	"client_secret": true,
+	"password":      true,
+	"private_key":   true,
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"token":         true,
+}
+
+// sensitiveValue finds sensitive values in bodies that aren't JSON, e.g. `password: hunter2` in YAML
+var sensitiveValue = regexp.MustCompile(`(?i)((?:secret|password|private[-_]key|token)["']?\s*[:=]\s*)("[^"]*"|'[^']*'|[^\s,&]+)`)
+
+// httpLogLevelEnvVars set the level of the HTTP traces, the first one set wins as the subsystem inherits the level of
+// the provider's logger, which inherits terraform's
+var httpLogLevelEnvVars = []string{"TF_LOG_PROVIDER_AIDBOX_HTTP", "TF_LOG_PROVIDER_AIDBOX", "TF_LOG_PROVIDER", "TF_LOG"}
+
+// httpLogDebugEnabled tells whether the HTTP traces would be logged at all
+func httpLogDebugEnabled() bool {
+	for _, envVar := range httpLogLevelEnvVars {
+		if level := os.Getenv(envVar); level != "" {
+			level = strings.ToUpper(level)
+			return level == "JSON" || level == "TRACE" || level == "DEBUG"
+		}
+	}
+	return false
+}
+
+// LoggingRoundTripper is an http.RoundTripper that traces the requests and responses through tflog at debug level,
+// masking credentials and secrets
 type LoggingRoundTripper struct {
 	// Proxied is the RoundTripper to which the request is delegated.
 	Proxied http.RoundTripper
+	// MaxBodySize is how many bytes of the bodies are logged, bodies aren't logged at all if zero
+	MaxBodySize int
+	// Debug enables the tracing, the requests are only passed on to Proxied otherwise
+	Debug bool
+}
+
+// newLoggingRoundTripper decides once whether to trace, rather than finding the bodies aren't logged after reading,
+// parsing and masking them for every request
+func newLoggingRoundTripper(proxied http.RoundTripper, maxBodySize int) LoggingRoundTripper {
+	return LoggingRoundTripper{
+		Proxied:     proxied,
+		MaxBodySize: maxBodySize,
+		Debug:       httpLogDebugEnabled(),
+	}
 }
 
 func (lrt LoggingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
-	// Log request
-	requestDump, err := httputil.DumpRequestOut(req, true)
-	if err != nil {
-		fmt.Printf("Error dumping request: %v\n", err)
-	} else {
-		fmt.Printf("--- REQUEST ---\n%s\n", string(requestDump))
+	if !lrt.Debug {
+		return lrt.Proxied.RoundTrip(req)
+	}
+	// every request comes with the logger of the terraform operation it's part of, which the subsystem has to be
+	// derived from
+	ctx := tflog.NewSubsystem(req.Context(), httpLogSubsystem, tflog.WithLevelFromEnv("TF_LOG_PROVIDER_AIDBOX", httpLogSubsystem))
+	requestFields := map[string]interface{}{
+		"method":  req.Method,
+		"url":     req.URL.String(),
+		"headers": redactHeaders(req.Header),
 	}
+	if req.GetBody != nil && lrt.MaxBodySize > 0 {
+		body, err := req.GetBody()
+		if err == nil {
+			requestBody, _ := io.ReadAll(body)
+			body.Close()
+			requestFields["body"] = lrt.formatBody(requestBody)
+		}
+	}
+	tflog.SubsystemDebug(ctx, httpLogSubsystem, "Sending request to aidbox", requestFields)
 
-	// Delegate the request to the original RoundTripper
 	resp, err := lrt.Proxied.RoundTrip(req)
-
 	if err != nil {
-		fmt.Printf("--- RESPONSE ERROR ---\nError making request: %v\n", err)
+		tflog.SubsystemDebug(ctx, httpLogSubsystem, "Request to aidbox failed", map[string]interface{}{
+			"method": req.Method,
+			"url":    req.URL.String(),
+			"error":  err.Error(),
+		})
 		return nil, err
 	}
 
-	// Log response
-	responseDump, err := httputil.DumpResponse(resp, true)
-	if err != nil {
-		fmt.Printf("Error dumping response: %v\n", err)
+	responseFields := map[string]interface{}{
+		"method":  req.Method,
+		"url":     req.URL.String(),
+		"status":  resp.Status,
+		"headers": redactHeaders(resp.Header),
+	}
+	if lrt.MaxBodySize > 0 {
+		responseBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = io.NopCloser(bytes.NewReader(responseBody))
+		responseFields["body"] = lrt.formatBody(responseBody)
+	}
+	tflog.SubsystemDebug(ctx, httpLogSubsystem, "Received response from aidbox", responseFields)
+	return resp, nil
+}
+
+func redactHeaders(header http.Header) map[string]string {
+	redactedHeaders := map[string]string{}
+	for name, values := range header {
+		if sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			redactedHeaders[name] = redacted
+		} else {
+			redactedHeaders[name] = strings.Join(values, ", ")
+		}
+	}
+	return redactedHeaders
+}
+
+// formatBody masks the secrets in the body, before truncating it to MaxBodySize
+func (lrt LoggingRoundTripper) formatBody(body []byte) string {
+	var content string
+	var parsed interface{}
+	if json.Unmarshal(body, &parsed) == nil {
+		redactedBody, _ := json.Marshal(redactJSON(parsed))
+		content = string(redactedBody)
 	} else {
-		fmt.Printf("--- RESPONSE ---\n%s\n", string(responseDump))
+		content = sensitiveValue.ReplaceAllString(string(body), "${1}"+redacted)
 	}
+	if len(content) > lrt.MaxBodySize {
+		return fmt.Sprintf("%s... (%d more bytes)", content[:lrt.MaxBodySize], len(content)-lrt.MaxBodySize)
+	}
+	return content
+}
 
-	return resp, err
+func redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if sensitiveFields[strings.ReplaceAll(strings.ToLower(key), "-", "_")] {
+				v[key] = redacted
+			} else {
+				v[key] = redactJSON(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactJSON(item)
+		}
+	}
+	return value
 }
@@ -2,9 +2,12 @@ package provider
 
 import (
 	"context"
+	"time"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
 )
 
@@ -47,14 +50,119 @@ func New(apiClient *aidbox.ApiClient) func() *schema.Provider {
 					Required:    true,
 					DefaultFunc: schema.EnvDefaultFunc("AIDBOX_URL", "http://localhost:8888/"),
 				},
+				"auth_method": {
+					Type: schema.TypeString,
+					Description: "How to authenticate to aidbox API. One of (basic|client_credentials). " +
+						"`basic` sends the client ID and secret with every request, `client_credentials` exchanges them " +
+						"for an access token at the `/auth/token` endpoint, which is cached and refreshed before it expires. " +
+						"The client must have the `client_credentials` grant type to use the latter.",
+					Optional:    true,
+					DefaultFunc: schema.EnvDefaultFunc("AIDBOX_AUTH_METHOD", string(aidbox.AuthMethodBasic)),
+					ValidateDiagFunc: func(i interface{}, path cty.Path) diag.Diagnostics {
+						_, err := aidbox.ParseAuthMethod(i.(string))
+						if err != nil {
+							return diag.FromErr(err)
+						}
+						return nil
+					},
+				},
+				"max_retries": {
+					Type: schema.TypeInt,
+					Description: "How many times to retry a request failing with a transient error (connection failure, " +
+						"429, 502, 503, 504), e.g. while aidbox is restarting. Only idempotent requests (GET, PUT, DELETE) " +
+						"are retried.",
+					Optional:     true,
+					Default:      3,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"retry_min_wait": {
+					Type:         schema.TypeInt,
+					Description:  "Seconds to wait before the first retry, doubled for every further retry.",
+					Optional:     true,
+					Default:      1,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"retry_max_wait": {
+					Type:         schema.TypeInt,
+					Description:  "Maximum seconds to wait between retries, also caps the wait requested by a `Retry-After` header.",
+					Optional:     true,
+					Default:      30,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"request_timeout": {
+					Type:         schema.TypeInt,
+					Description:  "Seconds a single request to aidbox API may take, including reading the response. No limit if 0.",
+					Optional:     true,
+					Default:      0,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"max_concurrent_requests": {
+					Type: schema.TypeInt,
+					Description: "Maximum number of requests to aidbox API in flight at once, across all resources. " +
+						"Useful to protect small aidbox instances during large applies. No limit if 0.",
+					Optional:     true,
+					Default:      0,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"requests_per_second": {
+					Type:         schema.TypeFloat,
+					Description:  "Maximum rate of requests to aidbox API, across all resources. No limit if 0.",
+					Optional:     true,
+					Default:      0,
+					ValidateFunc: validation.FloatAtLeast(0),
+				},
+				"http_log_max_body_size": {
+					Type: schema.TypeInt,
+					Description: "How many bytes of the request and response bodies to include in the HTTP traces logged " +
+						"with `TF_LOG=DEBUG` (or `TF_LOG_PROVIDER_AIDBOX_HTTP=DEBUG` for just the traces). Credentials " +
+						"and secrets are masked. Bodies aren't logged if 0.",
+					Optional:     true,
+					Default:      16384,
+					ValidateFunc: validation.IntAtLeast(0),
+				},
+				"ca_cert_pem": {
+					Type:        schema.TypeString,
+					Description: "PEM encoded CA certificate(s) to trust in addition to the system ones, e.g. if aidbox is behind an internal CA.",
+					Optional:    true,
+				},
+				"client_cert_pem": {
+					Type:         schema.TypeString,
+					Description:  "PEM encoded client certificate to present to aidbox for mTLS.",
+					Optional:     true,
+					RequiredWith: []string{"client_key_pem"},
+				},
+				"client_key_pem": {
+					Type:         schema.TypeString,
+					Description:  "PEM encoded private key of the client certificate.",
+					Optional:     true,
+					Sensitive:    true,
+					RequiredWith: []string{"client_cert_pem"},
+				},
+				"insecure_skip_verify": {
+					Type:        schema.TypeBool,
+					Description: "Don't verify the TLS certificate of aidbox. Only use this for local development.",
+					Optional:    true,
+					Default:     false,
+				},
+				"optimistic_locking": {
+					Type: schema.TypeBool,
+					Description: "Update resources only if they weren't changed since terraform last read them, using " +
+						"their version_id. Otherwise changes made outside of terraform, e.g. in the aidbox UI, are silently overwritten.",
+					Optional: true,
+					Default:  true,
+				},
 			},
 			DataSourcesMap: map[string]*schema.Resource{
-				"aidbox_user": dataSourceUser(),
+				"aidbox_user":                     dataSourceUser(),
+				"aidbox_resources":                dataSourceAidboxResources(),
+				"aidbox_resource":                 dataSourceAidboxResource(),
+				"aidbox_access_policy_evaluation": dataSourceAccessPolicyEvaluation(),
 			},
 			ResourcesMap: map[string]*schema.Resource{
 				"aidbox_token_introspector":            resourceTokenIntrospector(),
 				"aidbox_access_policy":                 resourceAccessPolicy(),
 				"aidbox_client":                        resourceClient(),
+				"aidbox_user":                          resourceUser(),
 				"aidbox_db_migration":                  resourceDbMigration(),
 				"aidbox_search":                        resourceSearch(),
 				"aidbox_search_parameter":              resourceSearchParameter(),
@@ -68,6 +176,8 @@ func New(apiClient *aidbox.ApiClient) func() *schema.Provider {
 				"aidbox_gcp_service_account":           resourceGcpServiceAccount(),
 				"aidbox_questionnaire_theme":           resourceQuestionnaireTheme(),
 				"aidbox_resource":                      resourceAidboxResource(),
+				"aidbox_bundle":                        resourceBundle(),
+				"aidbox_resource_set":                  resourceResourceSet(),
 			},
 		}
 
@@ -100,7 +210,34 @@ func New(apiClient *aidbox.ApiClient) func() *schema.Provider {
 			if !ok {
 				return nil, diag.Errorf("client_secret is wrong type")
 			}
-			return aidbox.NewApiClient(url, clientId, clientSecret), nil
+			authMethod, err := aidbox.ParseAuthMethod(rd.Get("auth_method").(string))
+			if err != nil {
+				return nil, diag.FromErr(err)
+			}
+			httpClient, err := aidbox.NewHTTPClient(aidbox.HTTPClientConfig{
+				CACertPEM:          rd.Get("ca_cert_pem").(string),
+				ClientCertPEM:      rd.Get("client_cert_pem").(string),
+				ClientKeyPEM:       rd.Get("client_key_pem").(string),
+				InsecureSkipVerify: rd.Get("insecure_skip_verify").(bool),
+				Timeout:            time.Duration(rd.Get("request_timeout").(int)) * time.Second,
+			})
+			if err != nil {
+				return nil, diag.FromErr(err)
+			}
+			httpClient.Transport = newLoggingRoundTripper(httpClient.Transport, rd.Get("http_log_max_body_size").(int))
+			client := aidbox.NewApiClient(url, clientId, clientSecret)
+			client.HTTPClient = httpClient
+			client.AuthMethod = authMethod
+			client.OptimisticLocking = rd.Get("optimistic_locking").(bool)
+			client.MaxConcurrentRequests = rd.Get("max_concurrent_requests").(int)
+			client.RequestsPerSecond = rd.Get("requests_per_second").(float64)
+			client.MaxRetries = rd.Get("max_retries").(int)
+			client.RetryMinWait = time.Duration(rd.Get("retry_min_wait").(int)) * time.Second
+			client.RetryMaxWait = time.Duration(rd.Get("retry_max_wait").(int)) * time.Second
+			if client.RetryMinWait > client.RetryMaxWait {
+				return nil, diag.Errorf("retry_min_wait must not be greater than retry_max_wait")
+			}
+			return client, nil
 		}
 
 		return p
@@ -1,7 +1,6 @@
 package provider
 
 import (
-	"net/http"
 	"os"
 	"testing"
 
@@ -35,10 +34,9 @@ func init() {
 			return testProvider, nil
 		},
 	}
-	if os.Getenv("TF_ACC_DUMP_HTTP") == "true" {
-		// Log the request-response pairs to see exactly what our tests are doing
-		http.DefaultClient.Transport = LoggingRoundTripper{Proxied: http.DefaultTransport}
-	}
+	// Trace the request-response pairs like the provider does, to see exactly what our tests are doing run them with
+	// TF_LOG_PROVIDER_AIDBOX_HTTP=DEBUG
+	apiClient.HTTPClient.Transport = newLoggingRoundTripper(apiClient.HTTPClient.Transport, 16384)
 }
 
 func TestProvider(t *testing.T) {
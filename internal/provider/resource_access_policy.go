@@ -3,16 +3,23 @@ package provider
 import (
 	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
 )
 
 func resourceAccessPolicy() *schema.Resource {
 	return &schema.Resource{
-		Description:   "AccessPolicy https://docs.aidbox.app/security-and-access-control-1/security/access-policy.",
+		Description: "AccessPolicy https://docs.aidbox.app/security-and-access-control-1/security/access-policy. " +
+			"The policy is checked when planning: the engine has to have its check set and no other's, `schema` has " +
+			"to compile as a JSON schema and `matcho` and `rpc` are warned about if they use unknown operators.",
 		CreateContext: resourceAccessPolicyCreate,
 		ReadContext:   resourceAccessPolicyRead,
 		UpdateContext: resourceAccessPolicyUpdate,
@@ -20,14 +27,30 @@ func resourceAccessPolicy() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceAccessPolicyImport,
 		},
-		Schema: resourceFullSchema(resourceSchemaAccessPolicy()),
+		CustomizeDiff: customizeAccessPolicyDiff,
+		Schema:        resourceFullSchema(resourceSchemaAccessPolicy()),
 	}
 }
 
-func mapAccessPolicyToData(res *aidbox.AccessPolicy, data *schema.ResourceData) {
-	data.SetId(res.ID)
+// accessPolicyLinkTypes are the types of resources a policy can be linked to
+var accessPolicyLinkTypes = []string{"Client", "User", "Operation"}
+
+// accessPolicyEngineFields are the attributes holding the check of each engine, one of which has to be set
+var accessPolicyEngineFields = map[aidbox.AccessPolicyEngine][]string{
+	aidbox.AccessPolicyEngineJsonSchema: {"schema"},
+	aidbox.AccessPolicyEngineMatcho:     {"matcho"},
+	aidbox.AccessPolicyEngineMatchoRpc:  {"rpc"},
+	aidbox.AccessPolicyEngineSql:        {"sql"},
+	aidbox.AccessPolicyEngineClj:        {"clj"},
+	aidbox.AccessPolicyEngineComplex:    {"and", "or"},
+}
+
+func mapAccessPolicyToData(res *aidbox.AccessPolicy, data *schema.ResourceData) diag.Diagnostics {
+	mapResourceBaseToData(&res.ResourceBase, data)
 	data.Set("description", res.Description)
-	data.Set("engine", res.Engine.ToString())
+	data.Set("role_name", res.RoleName)
+	data.Set("type", res.Type)
+	data.Set("engine", string(res.Engine))
 	var linkData []interface{}
 	for _, ref := range res.Link {
 		data := map[string]string{
@@ -46,17 +69,56 @@ func mapAccessPolicyToData(res *aidbox.AccessPolicy, data *schema.ResourceData)
 	if string(res.Rpc) != "" {
 		data.Set("rpc", string(res.Rpc))
 	}
+	data.Set("sql", mapAccessPolicySqlToData(res.Sql))
+	data.Set("clj", res.Clj)
+	diags := unknownEnumWarning(cty.GetAttrPath("engine"), res.Engine)
+	and, andDiags := mapAccessPolicyRulesToData(res.And, cty.GetAttrPath("and"))
+	data.Set("and", and)
+	or, orDiags := mapAccessPolicyRulesToData(res.Or, cty.GetAttrPath("or"))
+	data.Set("or", or)
+	return append(append(diags, andDiags...), orDiags...)
+}
+
+func mapAccessPolicySqlToData(sql *aidbox.AccessPolicySql) []interface{} {
+	if sql == nil {
+		return nil
+	}
+	return []interface{}{map[string]interface{}{"query": sql.Query}}
+}
+
+// mapAccessPolicyRulesToData maps the policies nested in a complex one, which can't be complex themselves
+func mapAccessPolicyRulesToData(rules []aidbox.AccessPolicyRule, path cty.Path) ([]interface{}, diag.Diagnostics) {
+	var rulesData []interface{}
+	var diags diag.Diagnostics
+	for i, rule := range rules {
+		rulePath := path.IndexInt(i)
+		rulesData = append(rulesData, map[string]interface{}{
+			"engine": string(rule.Engine),
+			"schema": string(rule.Schema),
+			"matcho": string(rule.Matcho),
+			"sql":    mapAccessPolicySqlToData(rule.Sql),
+			"clj":    rule.Clj,
+		})
+		diags = append(diags, unknownEnumWarning(rulePath.GetAttr("engine"), rule.Engine)...)
+		if len(rule.And) > 0 || len(rule.Or) > 0 {
+			diags = append(diags, diag.Diagnostic{
+				Severity:      diag.Warning,
+				Summary:       "Nested complex policy",
+				Detail:        "The and/or of policies nested in a complex policy aren't supported, they're left out of the state",
+				AttributePath: rulePath,
+			})
+		}
+	}
+	return rulesData, diags
 }
 
 func mapAccessPolicyFromData(d *schema.ResourceData) *aidbox.AccessPolicy {
 	res := &aidbox.AccessPolicy{}
-	res.ID = d.Id()
+	res.ResourceBase = mapResourceBaseFromData(d)
 	res.Description = d.Get("description").(string)
-	e, err := aidbox.ParseAccessPolicyEngine(d.Get("engine").(string))
-	if err != nil {
-		log.Panicln(err)
-	}
-	res.Engine = e
+	res.RoleName = d.Get("role_name").(string)
+	res.Type = d.Get("type").(string)
+	res.Engine = aidbox.AccessPolicyEngine(d.Get("engine").(string))
 	if v, ok := d.GetOk("link"); ok {
 		references := []aidbox.Reference{}
 		for _, data := range v.([]interface{}) {
@@ -78,18 +140,118 @@ func mapAccessPolicyFromData(d *schema.ResourceData) *aidbox.AccessPolicy {
 	if vv, ok := d.GetOk("rpc"); ok {
 		res.Rpc = json.RawMessage(vv.(string))
 	}
+	res.Sql = mapAccessPolicySqlFromData(d.Get("sql"))
+	res.Clj = d.Get("clj").(string)
+	res.And = mapAccessPolicyRulesFromData(d.Get("and"))
+	res.Or = mapAccessPolicyRulesFromData(d.Get("or"))
 	return res
 }
 
+func mapAccessPolicySqlFromData(v interface{}) *aidbox.AccessPolicySql {
+	sql := v.([]interface{})
+	if len(sql) == 0 || sql[0] == nil {
+		return nil
+	}
+	return &aidbox.AccessPolicySql{Query: sql[0].(map[string]interface{})["query"].(string)}
+}
+
+func mapAccessPolicyRulesFromData(v interface{}) []aidbox.AccessPolicyRule {
+	var rules []aidbox.AccessPolicyRule
+	for _, r := range v.([]interface{}) {
+		ruleData := r.(map[string]interface{})
+		rule := aidbox.AccessPolicyRule{
+			Engine: aidbox.AccessPolicyEngine(ruleData["engine"].(string)),
+			Sql:    mapAccessPolicySqlFromData(ruleData["sql"]),
+			Clj:    ruleData["clj"].(string),
+		}
+		if s := ruleData["schema"].(string); s != "" {
+			rule.Schema = json.RawMessage(s)
+		}
+		if m := ruleData["matcho"].(string); m != "" {
+			rule.Matcho = json.RawMessage(m)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// accessPolicyCheckFields are all the attributes holding the check of an engine
+var accessPolicyCheckFields = []string{"schema", "matcho", "rpc", "sql", "clj", "and", "or"}
+
+// configValueSet tells whether a known value of the configuration is set to something
+func configValueSet(value cty.Value) bool {
+	switch {
+	case value.IsNull():
+		return false
+	case value.Type().IsListType():
+		return value.LengthInt() > 0
+	case value.Type() == cty.String:
+		return value.AsString() != ""
+	}
+	return true
+}
+
+// checkAccessPolicyEngine reports a policy of the configuration whose engine is missing its check, or which has the
+// check of another engine. Values which aren't known yet are taken as set when required and as not set otherwise.
+func checkAccessPolicyEngine(policy cty.Value, prefix string) error {
+	engine := policy.GetAttr("engine")
+	if !engine.IsKnown() || engine.IsNull() || !aidbox.AccessPolicyEngine(engine.AsString()).IsKnown() {
+		return nil
+	}
+	fields := accessPolicyEngineFields[aidbox.AccessPolicyEngine(engine.AsString())]
+	var errs []error
+	if len(fields) > 0 {
+		missing := true
+		for _, field := range fields {
+			value := policy.GetAttr(field)
+			if !value.IsKnown() || configValueSet(value) {
+				missing = false
+			}
+		}
+		if missing {
+			errs = append(errs, fmt.Errorf("%sthe %s engine needs %s to be set", prefix, engine.AsString(), strings.Join(fields, " or ")))
+		}
+	}
+	for _, field := range accessPolicyCheckFields {
+		if slices.Contains(fields, field) || !policy.Type().HasAttribute(field) {
+			continue
+		}
+		value := policy.GetAttr(field)
+		if value.IsKnown() && configValueSet(value) {
+			errs = append(errs, fmt.Errorf("%sthe %s engine doesn't use %s, remove it", prefix, engine.AsString(), field))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func customizeAccessPolicyDiff(ctx context.Context, rd *schema.ResourceDiff, meta interface{}) error {
+	config := rd.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return nil
+	}
+	errs := []error{checkAccessPolicyEngine(config, "")}
+	for _, attribute := range []string{"and", "or"} {
+		rules := config.GetAttr(attribute)
+		if rules.IsNull() || !rules.IsKnown() {
+			continue
+		}
+		for it := rules.ElementIterator(); it.Next(); {
+			i, rule := it.Element()
+			index, _ := i.AsBigFloat().Int64()
+			errs = append(errs, checkAccessPolicyEngine(rule, fmt.Sprintf("%s.%d: ", attribute, index)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func resourceAccessPolicyCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	q := mapAccessPolicyFromData(d)
 	res, err := apiClient.CreateAccessPolicy(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAccessPolicy())
 	}
-	mapAccessPolicyToData(res, d)
-	return nil
+	return mapAccessPolicyToData(res, d)
 }
 
 func resourceAccessPolicyRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -99,10 +261,9 @@ func resourceAccessPolicyRead(ctx context.Context, d *schema.ResourceData, meta
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAccessPolicy())
 	}
-	mapAccessPolicyToData(res, d)
-	return nil
+	return mapAccessPolicyToData(res, d)
 }
 
 func resourceAccessPolicyUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -110,17 +271,16 @@ func resourceAccessPolicyUpdate(ctx context.Context, d *schema.ResourceData, met
 	q := mapAccessPolicyFromData(d)
 	ti, err := apiClient.UpdateAccessPolicy(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAccessPolicy())
 	}
-	mapAccessPolicyToData(ti, d)
-	return nil
+	return mapAccessPolicyToData(ti, d)
 }
 
 func resourceAccessPolicyDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteAccessPolicy(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAccessPolicy())
 	}
 	return nil
 }
@@ -131,7 +291,9 @@ func resourceAccessPolicyImport(ctx context.Context, d *schema.ResourceData, met
 	if err != nil {
 		return nil, err
 	}
-	mapAccessPolicyToData(res, d)
+	if err := logImportDiagnostics(ctx, mapAccessPolicyToData(res, d)); err != nil {
+		return nil, err
+	}
 	return []*schema.ResourceData{d}, nil
 }
 
@@ -142,31 +304,53 @@ func resourceSchemaAccessPolicy() map[string]*schema.Schema {
 			Type:        schema.TypeString,
 			Optional:    true,
 		},
+		"role_name": {
+			Description: "Name of the role the policy applies to, it's then only evaluated for users and clients with that role",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"type": {
+			Description: "Type of the policy, e.g. `scope` for a policy evaluated for the SMART scopes granted to a client",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
 		"engine": {
-			Description: "The engine which is used to evaluate this policy. One of (json-schema|matcho|matcho-rpc|allow)",
+			Description:  "The engine which is used to evaluate this policy. One of (json-schema|matcho|matcho-rpc|allow|sql|complex|clj)",
+			Type:         schema.TypeString,
+			Required:     true,
+			ValidateFunc: validation.StringInSlice(aidbox.AccessPolicyEngines.Strings(), false),
+		},
+		"sql": accessPolicySqlSchema(),
+		"clj": {
+			Description: "Clojure function deciding on the request. Used only if the engine is clj",
 			Type:        schema.TypeString,
-			Required:    true,
+			Optional:    true,
 		},
+		"and": accessPolicyRulesSchema("Policies which all have to grant the request. Used only if the engine is complex"),
+		"or":  accessPolicyRulesSchema("Policies of which one has to grant the request. Used only if the engine is complex"),
 		"schema": {
 			Description:      "JSON-schema policy to be evaluated. Used only if engine is json-schema",
 			Type:             schema.TypeString,
 			Optional:         true,
 			DiffSuppressFunc: jsonDiffSuppressFunc,
+			ValidateDiagFunc: validateAccessPolicySchema,
 		},
 		"matcho": {
 			Description:      "Matcho policy to be evaluated. Used only if the engine is matcho",
 			Type:             schema.TypeString,
 			Optional:         true,
 			DiffSuppressFunc: jsonDiffSuppressFunc,
+			ValidateDiagFunc: validateMatcho,
 		},
 		"rpc": {
 			Description:      "Rpc policy to be evaluated. Used only if the engine is matcho-rpc",
 			Type:             schema.TypeString,
 			Optional:         true,
 			DiffSuppressFunc: jsonDiffSuppressFunc,
+			ValidateDiagFunc: validateMatcho,
 		},
 		"link": {
-			Description: "The actor to allow access. Used only if engine is allow.",
+			Description: "The clients, users or operations the policy applies to. With the allow engine, they're granted access to everything.",
 			Type:        schema.TypeList,
 			Optional:    true,
 			Elem: &schema.Resource{
@@ -177,12 +361,76 @@ func resourceSchemaAccessPolicy() map[string]*schema.Schema {
 						Required:    true,
 					},
 					"resource_type": {
-						Description: "The type of the referenced resource (Client)",
-						Type:        schema.TypeString,
-						Required:    true,
+						Description:  "The type of the referenced resource. One of (Client|User|Operation)",
+						Type:         schema.TypeString,
+						Required:     true,
+						ValidateFunc: validation.StringInSlice(accessPolicyLinkTypes, false),
 					},
 				},
 			},
 		},
 	}
 }
+
+func accessPolicySqlSchema() *schema.Schema {
+	return &schema.Schema{
+		Description: "SQL policy to be evaluated. Used only if the engine is sql",
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"query": {
+					Description: "Query granting access if it returns true, the request can be referred to with {{...}}, e.g. {{user.id}}",
+					Type:        schema.TypeString,
+					Required:    true,
+				},
+			},
+		},
+	}
+}
+
+// accessPolicyRulesSchema is the schema of the policies nested in a complex one, which can't be complex themselves
+func accessPolicyRulesSchema(description string) *schema.Schema {
+	var engines []string
+	for _, engine := range aidbox.AccessPolicyEngines.Strings() {
+		if engine != string(aidbox.AccessPolicyEngineComplex) {
+			engines = append(engines, engine)
+		}
+	}
+	return &schema.Schema{
+		Description: description,
+		Type:        schema.TypeList,
+		Optional:    true,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"engine": {
+					Description:  "The engine which is used to evaluate this policy, any but complex",
+					Type:         schema.TypeString,
+					Required:     true,
+					ValidateFunc: validation.StringInSlice(engines, false),
+				},
+				"schema": {
+					Description:      "JSON-schema policy to be evaluated. Used only if engine is json-schema",
+					Type:             schema.TypeString,
+					Optional:         true,
+					DiffSuppressFunc: jsonDiffSuppressFunc,
+					ValidateDiagFunc: validateAccessPolicySchema,
+				},
+				"matcho": {
+					Description:      "Matcho policy to be evaluated. Used only if the engine is matcho",
+					Type:             schema.TypeString,
+					Optional:         true,
+					DiffSuppressFunc: jsonDiffSuppressFunc,
+					ValidateDiagFunc: validateMatcho,
+				},
+				"sql": accessPolicySqlSchema(),
+				"clj": {
+					Description: "Clojure function deciding on the request. Used only if the engine is clj",
+					Type:        schema.TypeString,
+					Optional:    true,
+				},
+			},
+		},
+	}
+}
@@ -3,7 +3,9 @@ package provider
 import (
 	"testing"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -100,6 +102,90 @@ func TestAccResourceAccessPolicy_matcho_rpc(t *testing.T) {
 	})
 }
 
+func TestAccResourceAccessPolicy_sql(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceAccessPolicy_sql,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_access_policy.mysqlpolicy", "engine", "sql"),
+					resource.TestCheckResourceAttr("aidbox_access_policy.mysqlpolicy", "role_name", "practitioner"),
+					resource.TestCheckResourceAttr("aidbox_access_policy.mysqlpolicy", "sql.0.query", "SELECT {{user.id}} IS NOT NULL"),
+				),
+			},
+		},
+	})
+}
+
+func TestAccResourceAccessPolicy_complex(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceAccessPolicy_complex,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_access_policy.mycomplexpolicy", "engine", "complex"),
+					resource.TestCheckResourceAttr("aidbox_access_policy.mycomplexpolicy", "and.#", "2"),
+					resource.TestCheckResourceAttr("aidbox_access_policy.mycomplexpolicy", "and.0.engine", "sql"),
+					resource.TestCheckResourceAttr("aidbox_access_policy.mycomplexpolicy", "and.1.engine", "matcho"),
+					resource.TestCheckResourceAttr("aidbox_access_policy.mycomplexpolicy", "link.0.resource_type", "Operation"),
+				),
+			},
+		},
+	})
+}
+
+func TestMapAccessPolicy(t *testing.T) {
+	policy := &aidbox.AccessPolicy{
+		ResourceBase: aidbox.ResourceBase{ID: "policy"},
+		RoleName:     "practitioner",
+		Engine:       aidbox.AccessPolicyEngineComplex,
+		Link:         []aidbox.Reference{{ResourceId: "FhirRead", ResourceType: "Operation"}},
+		And: []aidbox.AccessPolicyRule{
+			{Engine: aidbox.AccessPolicyEngineSql, Sql: &aidbox.AccessPolicySql{Query: "SELECT true"}},
+			{Engine: aidbox.AccessPolicyEngineMatcho, Matcho: []byte(`{"request-method": "get"}`)},
+		},
+	}
+	d := resourceAccessPolicy().Data(nil)
+
+	diags := mapAccessPolicyToData(policy, d)
+
+	assert.Empty(t, diags)
+	assert.Equal(t, policy, mapAccessPolicyFromData(d))
+}
+
+func TestCheckAccessPolicyEngine(t *testing.T) {
+	sqlType := cty.List(cty.Object(map[string]cty.Type{"query": cty.String}))
+	policy := func(engine cty.Value, sql cty.Value) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{"engine": engine, "sql": sql})
+	}
+
+	assert.Equal(t, nil, checkAccessPolicyEngine(policy(cty.StringVal("sql"),
+		cty.ListVal([]cty.Value{cty.ObjectVal(map[string]cty.Value{"query": cty.StringVal("SELECT true")})})), ""))
+	assert.Equal(t, nil, checkAccessPolicyEngine(policy(cty.StringVal("sql"), cty.UnknownVal(sqlType)), ""))
+	assert.Equal(t, nil, checkAccessPolicyEngine(policy(cty.UnknownVal(cty.String), cty.ListValEmpty(sqlType.ElementType())), ""))
+	assert.Equal(t, nil, checkAccessPolicyEngine(policy(cty.StringVal("allow"), cty.NullVal(sqlType)), ""))
+	assert.EqualError(t, checkAccessPolicyEngine(policy(cty.StringVal("sql"), cty.ListValEmpty(sqlType.ElementType())), "and.0: "),
+		"and.0: the sql engine needs sql to be set")
+	assert.EqualError(t, checkAccessPolicyEngine(policy(cty.StringVal("sql"), cty.NullVal(sqlType)), ""),
+		"the sql engine needs sql to be set")
+
+	matchoPolicy := func(engine string) cty.Value {
+		return cty.ObjectVal(map[string]cty.Value{
+			"engine": cty.StringVal(engine),
+			"schema": cty.NullVal(cty.String),
+			"matcho": cty.StringVal(`{"request-method": "get"}`),
+		})
+	}
+	assert.Equal(t, nil, checkAccessPolicyEngine(matchoPolicy("matcho"), ""))
+	assert.EqualError(t, checkAccessPolicyEngine(matchoPolicy("json-schema"), ""),
+		"the json-schema engine needs schema to be set\nthe json-schema engine doesn't use matcho, remove it")
+	assert.EqualError(t, checkAccessPolicyEngine(matchoPolicy("allow"), ""), "the allow engine doesn't use matcho, remove it")
+}
+
 const testAccResourceAccessPolicy_schema = `
 resource "aidbox_access_policy" "example" {
   description = "A policy to allow postman to access data"
@@ -268,3 +354,35 @@ const matcho_rpc = `
     "request-method": "get"
   }
 `
+
+const testAccResourceAccessPolicy_sql = `
+resource "aidbox_access_policy" "mysqlpolicy" {
+  description = "A policy defined with sql"
+  engine      = "sql"
+  role_name   = "practitioner"
+  sql {
+    query = "SELECT {{user.id}} IS NOT NULL"
+  }
+}
+`
+
+const testAccResourceAccessPolicy_complex = `
+resource "aidbox_access_policy" "mycomplexpolicy" {
+  description = "A policy combining sql and matcho"
+  engine      = "complex"
+  and {
+    engine = "sql"
+    sql {
+      query = "SELECT {{user.id}} IS NOT NULL"
+    }
+  }
+  and {
+    engine = "matcho"
+    matcho = jsonencode({ "request-method" = "get" })
+  }
+  link {
+    resource_id   = "FhirRead"
+    resource_type = "Operation"
+  }
+}
+`
@@ -55,7 +55,7 @@ func resourceSchemaAidboxSubscriptionTopic() map[string]*schema.Schema {
 
 func mapAidboxSubscriptionTopicFromData(data *schema.ResourceData) (*aidbox.AidboxSubscriptionTopic, error) {
 	res := &aidbox.AidboxSubscriptionTopic{}
-	res.ID = data.Id()
+	res.ResourceBase = mapResourceBaseFromData(data)
 	res.Url = data.Get("url").(string)
 	res.Status = data.Get("status").(string)
 
@@ -74,7 +74,7 @@ func mapAidboxSubscriptionTopicFromData(data *schema.ResourceData) (*aidbox.Aidb
 }
 
 func mapAidboxSubscriptionTopicToData(res *aidbox.AidboxSubscriptionTopic, data *schema.ResourceData) {
-	data.SetId(res.ID)
+	mapResourceBaseToData(&res.ResourceBase, data)
 	data.Set("url", res.Url)
 	data.Set("status", res.Status)
 
@@ -92,11 +92,11 @@ func resourceAidboxSubscriptionTopicCreate(ctx context.Context, d *schema.Resour
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapAidboxSubscriptionTopicFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAidboxSubscriptionTopic())
 	}
 	res, err := apiClient.CreateAidboxSubscriptionTopic(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAidboxSubscriptionTopic())
 	}
 	mapAidboxSubscriptionTopicToData(res, d)
 	return nil
@@ -109,7 +109,7 @@ func resourceAidboxSubscriptionTopicRead(ctx context.Context, d *schema.Resource
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAidboxSubscriptionTopic())
 	}
 	mapAidboxSubscriptionTopicToData(res, d)
 	return nil
@@ -119,11 +119,11 @@ func resourceAidboxSubscriptionTopicUpdate(ctx context.Context, d *schema.Resour
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapAidboxSubscriptionTopicFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAidboxSubscriptionTopic())
 	}
 	ac, err := apiClient.UpdateAidboxSubscriptionTopic(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAidboxSubscriptionTopic())
 	}
 	mapAidboxSubscriptionTopicToData(ac, d)
 	return nil
@@ -133,7 +133,7 @@ func resourceAidboxSubscriptionTopicDelete(ctx context.Context, d *schema.Resour
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteAidboxSubscriptionTopic(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAidboxSubscriptionTopic())
 	}
 	return nil
 }
@@ -97,7 +97,7 @@ func resourceSchemaAidboxTopicDestination() map[string]*schema.Schema {
 
 func mapAidboxTopicDestinationFromData(data *schema.ResourceData) (*aidbox.AidboxTopicDestination, error) {
 	res := &aidbox.AidboxTopicDestination{}
-	res.ID = data.Id()
+	res.ResourceBase = mapResourceBaseFromData(data)
 	res.Topic = data.Get("topic").(string)
 	res.Content = data.Get("content").(string)
 	res.IncludeEntryAction = data.Get("include_entry_action").(bool)
@@ -128,7 +128,7 @@ func mapAidboxTopicDestinationFromData(data *schema.ResourceData) (*aidbox.Aidbo
 }
 
 func mapAidboxTopicDestinationToData(res *aidbox.AidboxTopicDestination, data *schema.ResourceData) {
-	data.SetId(res.ID)
+	mapResourceBaseToData(&res.ResourceBase, data)
 	data.Set("topic", res.Topic)
 	data.Set("content", res.Content)
 	data.Set("include_entry_action", res.IncludeEntryAction)
@@ -151,11 +151,11 @@ func resourceAidboxTopicDestinationCreate(ctx context.Context, d *schema.Resourc
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapAidboxTopicDestinationFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAidboxTopicDestination())
 	}
 	res, err := apiClient.CreateAidboxTopicDestination(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAidboxTopicDestination())
 	}
 	mapAidboxTopicDestinationToData(res, d)
 	return nil
@@ -168,7 +168,7 @@ func resourceAidboxTopicDestinationRead(ctx context.Context, d *schema.ResourceD
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAidboxTopicDestination())
 	}
 	mapAidboxTopicDestinationToData(res, d)
 	return nil
@@ -178,7 +178,7 @@ func resourceAidboxTopicDestinationDelete(ctx context.Context, d *schema.Resourc
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteAidboxTopicDestination(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaAidboxTopicDestination())
 	}
 	return nil
 }
@@ -0,0 +1,351 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/id"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+)
+
+func resourceBundle() *schema.Resource {
+	return &schema.Resource{
+		Description: "Bundle of resources applied atomically as a FHIR transaction " +
+			"https://docs.aidbox.app/api-1/fhir-api/bundle#transaction, either every change to them is applied or none. " +
+			"Every resource must have an explicit `id`, by which it's tracked whatever its position in the list, so " +
+			"resources with server-assigned ids can't be part of a bundle. Only the resources that changed are sent on " +
+			"later applies, and resources removed from the list are deleted in the same transaction. " +
+			"Only the fields written in the resources are compared with the server, like `subset_match` of " +
+			"`aidbox_resource` does, so that fields the server adds such as `meta` don't show up as changes.",
+		CreateContext: resourceBundleCreate,
+		ReadContext:   resourceBundleRead,
+		UpdateContext: resourceBundleUpdate,
+		DeleteContext: resourceBundleDelete,
+		CustomizeDiff: customizeBundleDiff,
+		Schema:        resourceSchemaBundle(),
+	}
+}
+
+func resourceSchemaBundle() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"resources": {
+			Description: "Aidbox resources in JSON format, each with a `resourceType` and an explicit `id`",
+			Type:        schema.TypeList,
+			Required:    true,
+			MinItems:    1,
+			Elem: &schema.Schema{
+				Type:             schema.TypeString,
+				ValidateFunc:     validation.StringIsJSON,
+				DiffSuppressFunc: jsonDiffSuppressFunc,
+			},
+		},
+		"entries": {
+			Description: "The resources on the server, in the same order as `resources`",
+			Type:        schema.TypeList,
+			Computed:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"resource_type": {
+						Description: "Type of the resource",
+						Type:        schema.TypeString,
+						Computed:    true,
+					},
+					"resource_id": {
+						Description: "ID of the resource",
+						Type:        schema.TypeString,
+						Computed:    true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// bundleEntry is a resource of the bundle as it's tracked in the state
+type bundleEntry struct {
+	ResourceType string
+	ResourceId   string
+}
+
+func (e bundleEntry) typeAndId() string {
+	return e.ResourceType + "/" + e.ResourceId
+}
+
+// parseBundleEntry tells which resource the configured one is
+func parseBundleEntry(resource string) (bundleEntry, error) {
+	var h map[string]any
+	err := json.Unmarshal([]byte(resource), &h)
+	if err != nil {
+		return bundleEntry{}, err
+	}
+	entry := bundleEntry{}
+	entry.ResourceType, _ = h["resourceType"].(string)
+	if entry.ResourceType == "" {
+		return bundleEntry{}, errors.New("no 'resourceType' field in JSON body")
+	}
+	entry.ResourceId, _ = h["id"].(string)
+	if entry.ResourceId == "" {
+		return bundleEntry{}, errors.New("no 'id' field in JSON body, resources of a bundle are tracked by their id")
+	}
+	return entry, nil
+}
+
+func mapBundleEntriesFromData(v interface{}) []bundleEntry {
+	var entries []bundleEntry
+	for _, e := range v.([]interface{}) {
+		entryData := e.(map[string]interface{})
+		entries = append(entries, bundleEntry{
+			ResourceType: entryData["resource_type"].(string),
+			ResourceId:   entryData["resource_id"].(string),
+		})
+	}
+	return entries
+}
+
+func mapBundleEntriesToData(entries []bundleEntry) []interface{} {
+	var entriesData []interface{}
+	for _, entry := range entries {
+		entriesData = append(entriesData, map[string]interface{}{
+			"resource_type": entry.ResourceType,
+			"resource_id":   entry.ResourceId,
+		})
+	}
+	return entriesData
+}
+
+// bundleChanges is the transaction bringing the resources in the state in line with the configured ones
+type bundleChanges struct {
+	// Transaction holds the entries to submit, it's empty if nothing changed
+	Transaction []aidbox.BundleEntry
+	// Entries are the resulting resources, in the order they're configured
+	Entries []bundleEntry
+	// Resources are the configured resources
+	Resources []string
+	// Submitted is the index in Transaction of every configured resource, -1 if it's unchanged
+	Submitted []int
+	// Unchanged is the content in the state of every configured resource that isn't submitted
+	Unchanged []string
+}
+
+// planBundleChanges works out what has to be sent for the configured resources given those in the state, which are
+// empty when the bundle is created. The resources are matched by type and id rather than position, so that removing
+// one from the list leaves the others alone. A resource that's missing from the server has an empty content in the
+// state.
+func planBundleChanges(oldResources []string, oldEntries []bundleEntry, newResources []string) (*bundleChanges, error) {
+	previous := map[string]string{}
+	for i, old := range oldEntries {
+		previous[old.typeAndId()] = ""
+		if i < len(oldResources) {
+			previous[old.typeAndId()] = oldResources[i]
+		}
+	}
+	changes := &bundleChanges{Resources: newResources}
+	kept := map[string]bool{}
+	for i, resource := range newResources {
+		entry, err := parseBundleEntry(resource)
+		if err != nil {
+			return nil, fmt.Errorf("resource %d: %w", i, err)
+		}
+		if kept[entry.typeAndId()] {
+			return nil, fmt.Errorf("resource %d: %s is in the bundle more than once", i, entry.typeAndId())
+		}
+		kept[entry.typeAndId()] = true
+		changes.Entries = append(changes.Entries, entry)
+
+		if old := previous[entry.typeAndId()]; old != "" && jsonDiffSuppressFunc("", old, resource, nil) {
+			changes.Submitted = append(changes.Submitted, -1)
+			changes.Unchanged = append(changes.Unchanged, old)
+			continue
+		}
+		changes.Submitted = append(changes.Submitted, len(changes.Transaction))
+		changes.Unchanged = append(changes.Unchanged, "")
+		changes.Transaction = append(changes.Transaction, aidbox.BundleEntry{
+			Resource: json.RawMessage(resource),
+			Request:  &aidbox.BundleEntryRequest{Method: "PUT", Url: entry.typeAndId()},
+		})
+	}
+	for _, old := range oldEntries {
+		if kept[old.typeAndId()] || previous[old.typeAndId()] == "" {
+			continue
+		}
+		changes.Transaction = append(changes.Transaction, aidbox.BundleEntry{
+			Request: &aidbox.BundleEntryRequest{Method: "DELETE", Url: old.typeAndId()},
+		})
+	}
+	return changes, nil
+}
+
+// bundleResourceForState keeps only the fields of the resource read from the server that are configured, so that
+// those the server adds don't show up as changes. Without a configured content to compare with, e.g. when a resource
+// deleted outside of terraform reappeared, only the meta is left out.
+func bundleResourceForState(content json.RawMessage, configured string) (string, error) {
+	var h interface{}
+	err := json.Unmarshal(content, &h)
+	if err != nil {
+		return "", err
+	}
+	if configured == "" {
+		if m, ok := h.(map[string]interface{}); ok {
+			delete(m, "meta")
+		}
+	} else {
+		var pattern interface{}
+		err = json.Unmarshal([]byte(configured), &pattern)
+		if err != nil {
+			return "", err
+		}
+		h = jsonSubset(h, pattern)
+	}
+	resource, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	return string(resource), nil
+}
+
+// applyBundleChanges submits the transaction and records the resulting resources in the state
+func applyBundleChanges(ctx context.Context, apiClient *aidbox.ApiClient, changes *bundleChanges, d *schema.ResourceData) error {
+	response := &aidbox.Bundle{}
+	if len(changes.Transaction) > 0 {
+		var err error
+		response, err = apiClient.SubmitTransaction(ctx, changes.Transaction)
+		if err != nil {
+			return err
+		}
+	}
+	var resources []interface{}
+	for i, entry := range changes.Entries {
+		if changes.Submitted[i] < 0 {
+			resources = append(resources, changes.Unchanged[i])
+			continue
+		}
+		content := response.Entry[changes.Submitted[i]].Resource
+		if len(content) == 0 {
+			res, err := apiClient.GetGenericResource(ctx, entry.typeAndId())
+			if err != nil {
+				return err
+			}
+			content = res.ResourceContent
+		}
+		resource, err := bundleResourceForState(content, changes.Resources[i])
+		if err != nil {
+			return err
+		}
+		resources = append(resources, resource)
+	}
+	err := d.Set("resources", resources)
+	if err != nil {
+		return err
+	}
+	return d.Set("entries", mapBundleEntriesToData(changes.Entries))
+}
+
+func customizeBundleDiff(ctx context.Context, rd *schema.ResourceDiff, meta interface{}) error {
+	if !rd.NewValueKnown("resources") {
+		return rd.SetNewComputed("entries")
+	}
+	o, n := rd.GetChange("resources")
+	for i := range n.([]interface{}) {
+		if !rd.NewValueKnown(fmt.Sprintf("resources.%d", i)) {
+			return rd.SetNewComputed("entries")
+		}
+	}
+	changes, err := planBundleChanges(toStrings(o), mapBundleEntriesFromData(rd.Get("entries")), toStrings(n))
+	if err != nil {
+		return err
+	}
+	if len(changes.Transaction) > 0 {
+		return rd.SetNewComputed("entries")
+	}
+	return nil
+}
+
+func resourceBundleCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	changes, err := planBundleChanges(nil, nil, toStrings(d.Get("resources")))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = applyBundleChanges(ctx, apiClient, changes, d)
+	if err != nil {
+		return diagFromErr(err, nil)
+	}
+	d.SetId(id.UniqueId())
+	return nil
+}
+
+func resourceBundleRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	entries := mapBundleEntriesFromData(d.Get("entries"))
+	previous := toStrings(d.Get("resources"))
+	resources := make([]interface{}, len(entries))
+	found := 0
+	for i, entry := range entries {
+		res, err := apiClient.GetGenericResource(ctx, entry.typeAndId())
+		if err != nil {
+			if errors.Is(err, aidbox.NotFoundError) {
+				// left empty, so that it's created again on the next apply
+				resources[i] = ""
+				continue
+			}
+			return diagFromErr(err, nil)
+		}
+		configured := ""
+		if i < len(previous) {
+			configured = previous[i]
+		}
+		resources[i], err = bundleResourceForState(res.ResourceContent, configured)
+		if err != nil {
+			return diag.FromErr(err)
+		}
+		found++
+	}
+	if found == 0 {
+		log.Printf("[WARN] Removing bundle with id %s from state as none of its resources exist", d.Id())
+		d.SetId("")
+		return nil
+	}
+	err := d.Set("resources", resources)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func resourceBundleUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	o, n := d.GetChange("resources")
+	oldEntries, _ := d.GetChange("entries")
+	oldResources := toStrings(o)
+	changes, err := planBundleChanges(oldResources, mapBundleEntriesFromData(oldEntries), toStrings(n))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	err = applyBundleChanges(ctx, apiClient, changes, d)
+	if err != nil {
+		return diagFromErr(err, nil)
+	}
+	return nil
+}
+
+func resourceBundleDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	changes, err := planBundleChanges(toStrings(d.Get("resources")), mapBundleEntriesFromData(d.Get("entries")), nil)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	if len(changes.Transaction) == 0 {
+		return nil
+	}
+	_, err = apiClient.SubmitTransaction(ctx, changes.Transaction)
+	if err != nil {
+		return diagFromErr(err, nil)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccResourceBundle(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceBundle,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_bundle.related", "entries.#", "3"),
+					resource.TestCheckResourceAttr("aidbox_bundle.related", "entries.0.resource_type", "ValueSet"),
+					resource.TestCheckResourceAttr("aidbox_bundle.related", "entries.0.resource_id", "bundle-colours"),
+					resource.TestCheckResourceAttr("aidbox_bundle.related", "entries.1.resource_type", "AccessPolicy"),
+					resource.TestCheckResourceAttr("aidbox_bundle.related", "entries.1.resource_id", "bundle-colours-read"),
+					resource.TestCheckResourceAttr("aidbox_bundle.related", "entries.2.resource_type", "Questionnaire"),
+					resource.TestCheckResourceAttr("aidbox_bundle.related", "entries.2.resource_id", "bundle-favourite-colour"),
+				),
+			},
+			{
+				// the server adds meta and the likes, which mustn't show up as changes
+				Config:   testAccResourceBundle,
+				PlanOnly: true,
+			},
+			{
+				Config: testAccResourceBundle_updated,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_bundle.related", "entries.#", "2"),
+					resource.TestCheckResourceAttr("aidbox_bundle.related", "entries.0.resource_id", "bundle-colours"),
+					resource.TestCheckResourceAttrWith("aidbox_bundle.related", "resources.0", compIgnoreJsonDiff(bundleValueSetUpdated)),
+					resource.TestCheckResourceAttr("aidbox_bundle.related", "entries.1.resource_id", "bundle-favourite-colour"),
+					resource.TestCheckResourceAttrWith("aidbox_bundle.related", "resources.1", compIgnoreJsonDiff(bundleQuestionnaire)),
+				),
+			},
+		},
+	})
+}
+
+const bundleValueSet = `
+{
+  "resourceType": "ValueSet",
+  "id": "bundle-colours",
+  "status": "active"
+}
+`
+
+const bundleValueSetUpdated = `
+{
+  "resourceType": "ValueSet",
+  "id": "bundle-colours",
+  "status": "retired"
+}
+`
+
+const bundleQuestionnaire = `
+{
+  "resourceType": "Questionnaire",
+  "id": "bundle-favourite-colour",
+  "status": "draft"
+}
+`
+
+const testAccResourceBundle = `
+resource "aidbox_bundle" "related" {
+  resources = [
+    <<-EOT` + bundleValueSet + `EOT
+    ,
+    jsonencode({
+      resourceType = "AccessPolicy"
+      id           = "bundle-colours-read"
+      engine       = "allow"
+    }),
+    <<-EOT` + bundleQuestionnaire + `EOT
+  ]
+}
+`
+
+// updates the value set, deletes the access policy and leaves the questionnaire alone, although it moved up the list
+const testAccResourceBundle_updated = `
+resource "aidbox_bundle" "related" {
+  resources = [
+    <<-EOT` + bundleValueSetUpdated + `EOT
+    ,
+    <<-EOT` + bundleQuestionnaire + `EOT
+  ]
+}
+`
+
+func TestPlanBundleChanges(t *testing.T) {
+	t.Run("should put every resource on create", func(t *testing.T) {
+		changes, err := planBundleChanges(nil, nil, []string{
+			`{"resourceType": "ValueSet", "id": "colours"}`,
+			`{"resourceType": "Questionnaire", "id": "favourite-colour"}`,
+		})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []aidbox.BundleEntry{
+			{Resource: []byte(`{"resourceType": "ValueSet", "id": "colours"}`), Request: &aidbox.BundleEntryRequest{Method: "PUT", Url: "ValueSet/colours"}},
+			{Resource: []byte(`{"resourceType": "Questionnaire", "id": "favourite-colour"}`), Request: &aidbox.BundleEntryRequest{Method: "PUT", Url: "Questionnaire/favourite-colour"}},
+		}, changes.Transaction)
+		assert.Equal(t, []bundleEntry{
+			{ResourceType: "ValueSet", ResourceId: "colours"},
+			{ResourceType: "Questionnaire", ResourceId: "favourite-colour"},
+		}, changes.Entries)
+		assert.Equal(t, []int{0, 1}, changes.Submitted)
+	})
+
+	t.Run("should only send what changed and delete what was removed, wherever it is in the list", func(t *testing.T) {
+		oldResources := []string{
+			`{"resourceType": "AccessPolicy", "id": "read", "engine": "allow"}`,
+			`{"resourceType": "ValueSet", "id": "colours", "status": "active"}`,
+			`{"resourceType": "Questionnaire", "id": "favourite-colour", "status": "draft"}`,
+		}
+		oldEntries := []bundleEntry{
+			{ResourceType: "AccessPolicy", ResourceId: "read"},
+			{ResourceType: "ValueSet", ResourceId: "colours"},
+			{ResourceType: "Questionnaire", ResourceId: "favourite-colour"},
+		}
+
+		changes, err := planBundleChanges(oldResources, oldEntries, []string{
+			`{"status": "active", "id": "colours", "resourceType": "ValueSet"}`,
+			`{"resourceType": "Questionnaire", "id": "favourite-colour", "status": "active"}`,
+		})
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []aidbox.BundleEntry{
+			{Resource: []byte(`{"resourceType": "Questionnaire", "id": "favourite-colour", "status": "active"}`), Request: &aidbox.BundleEntryRequest{Method: "PUT", Url: "Questionnaire/favourite-colour"}},
+			{Request: &aidbox.BundleEntryRequest{Method: "DELETE", Url: "AccessPolicy/read"}},
+		}, changes.Transaction)
+		assert.Equal(t, []int{-1, 0}, changes.Submitted)
+		assert.Equal(t, oldResources[1], changes.Unchanged[0])
+	})
+
+	t.Run("should put back resources deleted outside of terraform", func(t *testing.T) {
+		changes, err := planBundleChanges(
+			[]string{""},
+			[]bundleEntry{{ResourceType: "ValueSet", ResourceId: "colours"}},
+			nil,
+		)
+
+		assert.Equal(t, nil, err)
+		assert.Empty(t, changes.Transaction)
+
+		changes, err = planBundleChanges(
+			[]string{""},
+			[]bundleEntry{{ResourceType: "ValueSet", ResourceId: "colours"}},
+			[]string{`{"resourceType": "ValueSet", "id": "colours"}`},
+		)
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []int{0}, changes.Submitted)
+		assert.Equal(t, "PUT", changes.Transaction[0].Request.Method)
+	})
+
+	t.Run("should fail on duplicates and resources without a type or id", func(t *testing.T) {
+		_, err := planBundleChanges(nil, nil, []string{`{"resourceType": "ValueSet", "id": "colours"}`, `{"resourceType": "ValueSet", "id": "colours"}`})
+		assert.ErrorContains(t, err, "ValueSet/colours is in the bundle more than once")
+
+		_, err = planBundleChanges(nil, nil, []string{`{"id": "colours"}`})
+		assert.ErrorContains(t, err, "resource 0: no 'resourceType' field")
+
+		_, err = planBundleChanges(nil, nil, []string{`{"resourceType": "ValueSet", "id": "colours"}`, `{"resourceType": "Questionnaire"}`})
+		assert.ErrorContains(t, err, "resource 1: no 'id' field")
+	})
+}
+
+func TestBundleResourceForState(t *testing.T) {
+	content := []byte(`{"resourceType": "ValueSet", "id": "colours", "status": "active", "meta": {"versionId": "2"}, "name": "Colours"}`)
+
+	resource, err := bundleResourceForState(content, `{"resourceType": "ValueSet", "id": "colours", "status": "draft"}`)
+	assert.Equal(t, nil, err)
+	assert.JSONEq(t, `{"resourceType": "ValueSet", "id": "colours", "status": "active"}`, resource)
+
+	resource, err = bundleResourceForState(content, "")
+	assert.Equal(t, nil, err)
+	assert.JSONEq(t, `{"resourceType": "ValueSet", "id": "colours", "status": "active", "name": "Colours"}`, resource)
+}
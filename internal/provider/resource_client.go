@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
 )
 
@@ -31,36 +33,197 @@ func resourceSchemaClient() map[string]*schema.Schema {
 			Required:    true,
 		},
 		"secret": {
-			Description: "Client secret used for authentication",
+			Description: "Client secret used for authentication, not needed by public clients e.g. using the implicit flow",
 			Type:        schema.TypeString,
-			Required:    true,
+			Optional:    true,
 			Sensitive:   true,
 		},
 		"grant_types": {
-			Description: "Grant type used for authentication (basic)",
+			Description: "Grant types the client may use, any of basic, authorization_code, code, password, client_credentials, implicit and refresh_token",
 			Type:        schema.TypeList,
 			Elem: &schema.Schema{
-				Type: schema.TypeString,
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringInSlice(aidbox.GrantTypes.Strings(), false),
 			},
 			Required: true,
 			MinItems: 1,
 		},
+		"first_party": {
+			Description: "Whether the client is a trusted first party app, which isn't asked for the user's consent",
+			Type:        schema.TypeBool,
+			Optional:    true,
+		},
+		"auth": {
+			Description: "Configuration of the OAuth 2.0 flows https://docs.aidbox.app/modules/security-and-access-control/auth",
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"authorization_code": clientAuthFlowSchema("Authorization code flow", "authorization_code"),
+					"client_credentials": clientAuthFlowSchema("Client credentials flow", "client_credentials"),
+					"password":           clientAuthFlowSchema("Resource owner password flow", "password"),
+					"implicit":           clientAuthFlowSchema("Implicit flow", "implicit"),
+				},
+			},
+		},
+		"smart": {
+			Description: "SMART on FHIR app details",
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"launch_uri": {
+						Description: "URL the EHR launches the app with",
+						Type:        schema.TypeString,
+						Optional:    true,
+					},
+					"name": {
+						Description: "Name of the app shown to users",
+						Type:        schema.TypeString,
+						Optional:    true,
+					},
+					"description": {
+						Description: "Description of the app shown to users",
+						Type:        schema.TypeString,
+						Optional:    true,
+					},
+				},
+			},
+		},
+	}
+}
+
+// clientAuthFlows lists the settings each OAuth 2.0 flow supports
+var clientAuthFlows = map[string][]string{
+	"authorization_code": {"redirect_uri", "access_token_expiration", "token_format", "refresh_token", "secret_required", "pkce"},
+	"client_credentials": {"access_token_expiration", "token_format", "refresh_token"},
+	"password":           {"access_token_expiration", "token_format", "refresh_token", "secret_required"},
+	"implicit":           {"redirect_uri", "access_token_expiration", "token_format"},
+}
+
+// clientAuthFlowAttributes are the settings of the OAuth 2.0 flows
+var clientAuthFlowAttributes = map[string]*schema.Schema{
+	"redirect_uri": {
+		Description: "URL the user is redirected to with the code or token",
+		Type:        schema.TypeString,
+		Optional:    true,
+	},
+	"access_token_expiration": {
+		Description: "Lifetime of the access tokens in seconds, the server's default if not set",
+		Type:        schema.TypeInt,
+		Optional:    true,
+	},
+	"token_format": {
+		Description:  "Format of the access tokens, jwt or opaque",
+		Type:         schema.TypeString,
+		Optional:     true,
+		Default:      string(aidbox.TokenFormatOpaque),
+		ValidateFunc: validation.StringInSlice(aidbox.TokenFormats.Strings(), false),
+	},
+	"refresh_token": {
+		Description: "Whether a refresh token is issued along the access token",
+		Type:        schema.TypeBool,
+		Optional:    true,
+	},
+	"secret_required": {
+		Description: "Whether the client has to authenticate with its secret",
+		Type:        schema.TypeBool,
+		Optional:    true,
+	},
+	"pkce": {
+		Description: "Whether PKCE is required",
+		Type:        schema.TypeBool,
+		Optional:    true,
+	},
+}
+
+func clientAuthFlowSchema(description string, flow string) *schema.Schema {
+	flowSchema := map[string]*schema.Schema{}
+	for _, attribute := range clientAuthFlows[flow] {
+		flowSchema[attribute] = clientAuthFlowAttributes[attribute]
+	}
+	return &schema.Schema{
+		Description: description,
+		Type:        schema.TypeList,
+		Optional:    true,
+		MaxItems:    1,
+		Elem: &schema.Resource{
+			Schema: flowSchema,
+		},
 	}
 }
 
-func mapClientToData(res *aidbox.Client, data *schema.ResourceData) {
-	data.SetId(res.ID)
+func mapClientToData(res *aidbox.Client, data *schema.ResourceData) diag.Diagnostics {
+	mapResourceBaseToData(&res.ResourceBase, data)
 	data.Set("name", res.ID)
 	data.Set("secret", res.Secret)
+	var diags diag.Diagnostics
 	var types []interface{}
-	for _, gt := range res.GrantTypes {
-		types = append(types, gt.ToString())
+	for i, gt := range res.GrantTypes {
+		types = append(types, string(gt))
+		diags = append(diags, unknownEnumWarning(cty.GetAttrPath("grant_types").IndexInt(i), gt)...)
 	}
 	data.Set("grant_types", types)
+	data.Set("first_party", res.FirstParty)
+
+	var auth []interface{}
+	if res.Auth != nil {
+		auth = []interface{}{map[string]interface{}{
+			"authorization_code": mapClientAuthFlowToData(res.Auth.AuthorizationCode, "authorization_code"),
+			"client_credentials": mapClientAuthFlowToData(res.Auth.ClientCredentials, "client_credentials"),
+			"password":           mapClientAuthFlowToData(res.Auth.Password, "password"),
+			"implicit":           mapClientAuthFlowToData(res.Auth.Implicit, "implicit"),
+		}}
+		flows := []*aidbox.ClientAuthFlow{res.Auth.AuthorizationCode, res.Auth.ClientCredentials, res.Auth.Password, res.Auth.Implicit}
+		for i, name := range []string{"authorization_code", "client_credentials", "password", "implicit"} {
+			if flows[i] != nil {
+				path := cty.GetAttrPath("auth").IndexInt(0).GetAttr(name).IndexInt(0).GetAttr("token_format")
+				diags = append(diags, unknownEnumWarning(path, flows[i].TokenFormat)...)
+			}
+		}
+	}
+	data.Set("auth", auth)
+
+	var smart []interface{}
+	if res.Smart != nil {
+		smart = []interface{}{map[string]interface{}{
+			"launch_uri":  res.Smart.LaunchUri,
+			"name":        res.Smart.Name,
+			"description": res.Smart.Description,
+		}}
+	}
+	data.Set("smart", smart)
+	return diags
+}
+
+// mapClientAuthFlowToData maps the settings the named flow supports
+func mapClientAuthFlowToData(flow *aidbox.ClientAuthFlow, name string) []interface{} {
+	if flow == nil {
+		return nil
+	}
+	tokenFormat := flow.TokenFormat
+	if tokenFormat == "" {
+		tokenFormat = aidbox.TokenFormatOpaque
+	}
+	settings := map[string]interface{}{
+		"redirect_uri":            flow.RedirectUri,
+		"access_token_expiration": flow.AccessTokenExpiration,
+		"token_format":            string(tokenFormat),
+		"refresh_token":           flow.RefreshToken,
+		"secret_required":         flow.SecretRequired,
+		"pkce":                    flow.Pkce,
+	}
+	flowData := map[string]interface{}{}
+	for _, attribute := range clientAuthFlows[name] {
+		flowData[attribute] = settings[attribute]
+	}
+	return []interface{}{flowData}
 }
 
 func mapClientFromData(d *schema.ResourceData) (*aidbox.Client, error) {
-	res := &aidbox.Client{}
+	res := &aidbox.Client{ResourceBase: mapResourceBaseFromData(d)}
 	res.ID = d.Get("name").(string)
 	res.Secret = d.Get("secret").(string)
 	types := d.Get("grant_types").([]interface{})
@@ -73,21 +236,83 @@ func mapClientFromData(d *schema.ResourceData) (*aidbox.Client, error) {
 		grantTypes = append(grantTypes, gt)
 	}
 	res.GrantTypes = grantTypes
+	res.FirstParty = d.Get("first_party").(bool)
+
+	if v, ok := d.GetOk("auth"); ok && v.([]interface{})[0] != nil {
+		authData := v.([]interface{})[0].(map[string]interface{})
+		res.Auth = &aidbox.ClientAuth{}
+		var err error
+		if res.Auth.AuthorizationCode, err = mapClientAuthFlowFromData(authData["authorization_code"]); err != nil {
+			return nil, err
+		}
+		if res.Auth.ClientCredentials, err = mapClientAuthFlowFromData(authData["client_credentials"]); err != nil {
+			return nil, err
+		}
+		if res.Auth.Password, err = mapClientAuthFlowFromData(authData["password"]); err != nil {
+			return nil, err
+		}
+		if res.Auth.Implicit, err = mapClientAuthFlowFromData(authData["implicit"]); err != nil {
+			return nil, err
+		}
+	}
+
+	if v, ok := d.GetOk("smart"); ok && v.([]interface{})[0] != nil {
+		smartData := v.([]interface{})[0].(map[string]interface{})
+		res.Smart = &aidbox.ClientSmart{
+			LaunchUri:   smartData["launch_uri"].(string),
+			Name:        smartData["name"].(string),
+			Description: smartData["description"].(string),
+		}
+	}
 	return res, nil
 }
 
+func mapClientAuthFlowFromData(v interface{}) (*aidbox.ClientAuthFlow, error) {
+	flows := v.([]interface{})
+	if len(flows) == 0 {
+		return nil, nil
+	}
+	flow := &aidbox.ClientAuthFlow{}
+	flowData, _ := flows[0].(map[string]interface{})
+	if redirectUri, ok := flowData["redirect_uri"]; ok {
+		flow.RedirectUri = redirectUri.(string)
+	}
+	if expiration, ok := flowData["access_token_expiration"]; ok {
+		flow.AccessTokenExpiration = expiration.(int)
+	}
+	if tokenFormat, ok := flowData["token_format"]; ok {
+		tf, err := aidbox.ParseTokenFormat(tokenFormat.(string))
+		if err != nil {
+			return nil, err
+		}
+		// opaque is the server's default and is left out of the resource
+		if tf != aidbox.TokenFormatOpaque {
+			flow.TokenFormat = tf
+		}
+	}
+	if refreshToken, ok := flowData["refresh_token"]; ok {
+		flow.RefreshToken = refreshToken.(bool)
+	}
+	if secretRequired, ok := flowData["secret_required"]; ok {
+		flow.SecretRequired = secretRequired.(bool)
+	}
+	if pkce, ok := flowData["pkce"]; ok {
+		flow.Pkce = pkce.(bool)
+	}
+	return flow, nil
+}
+
 func resourceClientCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapClientFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaClient())
 	}
 	res, err := apiClient.CreateClient(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaClient())
 	}
-	mapClientToData(res, d)
-	return nil
+	return mapClientToData(res, d)
 }
 
 func resourceClientRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -97,31 +322,29 @@ func resourceClientRead(ctx context.Context, d *schema.ResourceData, meta interf
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaClient())
 	}
-	mapClientToData(res, d)
-	return nil
+	return mapClientToData(res, d)
 }
 
 func resourceClientUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapClientFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaClient())
 	}
 	ac, err := apiClient.UpdateClient(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaClient())
 	}
-	mapClientToData(ac, d)
-	return nil
+	return mapClientToData(ac, d)
 }
 
 func resourceClientDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteClient(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaClient())
 	}
 	return nil
 }
@@ -132,6 +355,8 @@ func resourceClientImport(ctx context.Context, d *schema.ResourceData, meta inte
 	if err != nil {
 		return nil, err
 	}
-	mapClientToData(res, d)
+	if err := logImportDiagnostics(ctx, mapClientToData(res, d)); err != nil {
+		return nil, err
+	}
 	return []*schema.ResourceData{d}, nil
 }
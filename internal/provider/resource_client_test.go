@@ -3,7 +3,10 @@ package provider
 import (
 	"testing"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestAccResourceClient_basic(t *testing.T) {
@@ -46,3 +49,78 @@ resource "aidbox_client" "example" {
   grant_types = ["basic"]
 }
 `
+
+func TestAccResourceClient_auth(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceClient_auth,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_client.smart_app", "id", "smart-app"),
+					resource.TestCheckResourceAttr("aidbox_client.smart_app", "grant_types.#", "3"),
+					resource.TestCheckResourceAttr("aidbox_client.smart_app", "first_party", "true"),
+					resource.TestCheckResourceAttr("aidbox_client.smart_app", "auth.0.authorization_code.0.redirect_uri", "https://app.example.com/callback"),
+					resource.TestCheckResourceAttr("aidbox_client.smart_app", "auth.0.authorization_code.0.token_format", "jwt"),
+					resource.TestCheckResourceAttr("aidbox_client.smart_app", "auth.0.authorization_code.0.refresh_token", "true"),
+					resource.TestCheckResourceAttr("aidbox_client.smart_app", "auth.0.authorization_code.0.secret_required", "true"),
+					resource.TestCheckResourceAttr("aidbox_client.smart_app", "auth.0.client_credentials.0.access_token_expiration", "300"),
+					resource.TestCheckResourceAttr("aidbox_client.smart_app", "auth.0.client_credentials.0.token_format", "opaque"),
+					resource.TestCheckResourceAttr("aidbox_client.smart_app", "smart.0.launch_uri", "https://app.example.com/launch"),
+				),
+			},
+			{
+				ResourceName:            "aidbox_client.smart_app",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"secret"},
+			},
+		},
+	})
+}
+
+const testAccResourceClient_auth = `
+resource "aidbox_client" "smart_app" {
+  name        = "smart-app"
+  secret      = "secret"
+  grant_types = ["authorization_code", "client_credentials", "refresh_token"]
+  first_party = true
+  auth {
+    authorization_code {
+      redirect_uri    = "https://app.example.com/callback"
+      token_format    = "jwt"
+      refresh_token   = true
+      secret_required = true
+    }
+    client_credentials {
+      access_token_expiration = 300
+    }
+  }
+  smart {
+    launch_uri = "https://app.example.com/launch"
+    name       = "Example app"
+  }
+}
+`
+
+func TestMapClientToData(t *testing.T) {
+	d := resourceClient().Data(nil)
+
+	diags := mapClientToData(&aidbox.Client{
+		ResourceBase: aidbox.ResourceBase{ID: "client"},
+		GrantTypes:   []aidbox.GrantType{aidbox.GrantTypeClientCredentials, "device_code"},
+		Auth: &aidbox.ClientAuth{
+			ClientCredentials: &aidbox.ClientAuthFlow{},
+			Password:          &aidbox.ClientAuthFlow{TokenFormat: "paseto"},
+		},
+	}, d)
+
+	assert.Len(t, diags, 2)
+	assert.Equal(t, cty.GetAttrPath("grant_types").IndexInt(1), diags[0].AttributePath)
+	assert.Equal(t, `Unknown value "paseto"`, diags[1].Summary)
+	assert.Equal(t, []interface{}{"client_credentials", "device_code"}, d.Get("grant_types"))
+	// a flow without a token format issues opaque tokens
+	assert.Equal(t, "opaque", d.Get("auth.0.client_credentials.0.token_format"))
+	assert.Equal(t, "paseto", d.Get("auth.0.password.0.token_format"))
+}
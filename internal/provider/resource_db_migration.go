@@ -21,7 +21,8 @@ func resourceDbMigration() *schema.Resource {
 		Importer: &schema.ResourceImporter{
 			StateContext: resourceDbMigrationImport,
 		},
-		Schema: resourceFullSchema(resourceSchemaDbMigration()),
+		// migrations aren't resources, they have no version or timestamps
+		Schema: resourceSchemaDbMigration(),
 	}
 }
 
@@ -43,7 +44,7 @@ func resourceDbMigrationCreate(ctx context.Context, data *schema.ResourceData, m
 	migration := mapDbMigrationFromData(data)
 	result, err := apiClient.CreateDbMigration(ctx, migration)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaDbMigration())
 	}
 	mapDbMigrationToData(result, data)
 	return nil
@@ -56,7 +57,7 @@ func resourceDbMigrationRead(ctx context.Context, data *schema.ResourceData, met
 		if handleNotFoundError(err, data) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaDbMigration())
 	}
 	mapDbMigrationToData(result, data)
 	return nil
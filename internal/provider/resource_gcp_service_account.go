@@ -44,7 +44,7 @@ func resourceSchemaGcpServiceAccount() map[string]*schema.Schema {
 }
 
 func mapGcpServiceAccountFromData(data *schema.ResourceData) (*aidbox.GcpServiceAccount, error) {
-	res := &aidbox.GcpServiceAccount{}
+	res := &aidbox.GcpServiceAccount{ResourceBase: mapResourceBaseFromData(data)}
 	res.ResourceType = "GcpServiceAccount"
 	// Deliberately use the 'name' field from the Terraform config
 	// as the resource 'ID' because 'GcpServiceAccount' is identified
@@ -62,7 +62,7 @@ func mapGcpServiceAccountFromData(data *schema.ResourceData) (*aidbox.GcpService
 }
 
 func mapGcpServiceAccountToData(res *aidbox.GcpServiceAccount, data *schema.ResourceData) error {
-	data.SetId(res.ID)
+	mapResourceBaseToData(&res.ResourceBase, data)
 	// Deliberately use the 'name' field from the Terraform config
 	// as the resource 'ID' because 'GcpServiceAccount' is identified
 	// by its name in Aidbox (not a server-generated UUID).
@@ -76,16 +76,16 @@ func resourceGcpServiceAccountCreate(ctx context.Context, d *schema.ResourceData
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapGcpServiceAccountFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaGcpServiceAccount())
 	}
 
 	res, err := apiClient.CreateGcpServiceAccount(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaGcpServiceAccount())
 	}
 	err = mapGcpServiceAccountToData(res, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaGcpServiceAccount())
 	}
 	return nil
 }
@@ -97,11 +97,11 @@ func resourceGcpServiceAccountRead(ctx context.Context, d *schema.ResourceData,
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaGcpServiceAccount())
 	}
 	err = mapGcpServiceAccountToData(res, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaGcpServiceAccount())
 	}
 	return nil
 }
@@ -110,15 +110,15 @@ func resourceGcpServiceAccountUpdate(ctx context.Context, d *schema.ResourceData
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapGcpServiceAccountFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaGcpServiceAccount())
 	}
 	ac, err := apiClient.UpdateGcpServiceAccount(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaGcpServiceAccount())
 	}
 	err = mapGcpServiceAccountToData(ac, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaGcpServiceAccount())
 	}
 	return nil
 }
@@ -127,7 +127,7 @@ func resourceGcpServiceAccountDelete(ctx context.Context, d *schema.ResourceData
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteGcpServiceAccount(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaGcpServiceAccount())
 	}
 	return nil
 }
@@ -3,9 +3,17 @@ package provider
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strings"
 
+	"github.com/hashicorp/terraform-plugin-log/tflog"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
 )
 
@@ -25,67 +33,260 @@ func resourceAidboxResource() *schema.Resource {
 	}
 }
 
-func customizeAidboxResourceDiff(_ context.Context, rd *schema.ResourceDiff, _ interface{}) error {
-	r1, r2 := rd.GetChange("resource")
+func customizeAidboxResourceDiff(ctx context.Context, rd *schema.ResourceDiff, meta interface{}) error {
+	if !rd.NewValueKnown("resource") || !rd.NewValueKnown("resource_yaml") {
+		return nil
+	}
+	r1, r2, err := resourceContentChange(rd)
+	if err != nil {
+		return err
+	}
+	if rd.Get("validate_on_plan").(bool) && r2 != "" {
+		err := validateAidboxResource(ctx, r2, meta.(*aidbox.ApiClient))
+		if err != nil {
+			return err
+		}
+	}
+	if rd.Get("delete_mode").(string) == "purge" && r2 != "" {
+		resourceType, err := aidbox.GetResourceType([]byte(r2))
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(aidbox.PurgeableResourceTypes, resourceType) {
+			return fmt.Errorf("delete_mode purge can't be used for a %s, %s", resourceType, aidbox.ErrNotPurgeable)
+		}
+	}
+	conditional := rd.Get("conditional_create_query").(string) != "" || rd.Get("conditional_update_query").(string) != ""
+	if conditional && r2 != "" {
+		_, err := aidbox.GetResourceTypeAndId([]byte(r2))
+		if err == nil {
+			return errors.New("conditional_create_query and conditional_update_query can only be used with resources " +
+				"without an id, the server finds the resource to create or update instead")
+		}
+	}
 	if r1 == "" {
 		return nil
 	}
 	var r1m map[string]interface{}
 	var r2m map[string]interface{}
-	err := json.Unmarshal([]byte(r1.(string)), &r1m)
+	err = json.Unmarshal([]byte(r1), &r1m)
 	if err != nil {
 		return err
 	}
-	err = json.Unmarshal([]byte(r2.(string)), &r2m)
+	err = json.Unmarshal([]byte(r2), &r2m)
 	if err != nil {
 		return err
 	}
 	id1, _ := r1m["id"]
 	id2, _ := r2m["id"]
-	if id1 != id2 {
-		err = rd.ForceNew("resource")
+	rt1, _ := r1m["resourceType"]
+	rt2, _ := r2m["resourceType"]
+	if id1 != id2 || rt1 != rt2 {
+		// whichever of the two attributes the resource is in
+		for _, key := range []string{"resource", "resource_yaml"} {
+			if rd.HasChange(key) {
+				err = rd.ForceNew(key)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resourceContentChange is the old and new content of the resource in JSON, whether it's written in JSON or in YAML
+func resourceContentChange(rd *schema.ResourceDiff) (string, string, error) {
+	o, n := rd.GetChange("resource")
+	oldContent, newContent := o.(string), n.(string)
+	oldYaml, newYaml := rd.GetChange("resource_yaml")
+	var err error
+	if oldYaml.(string) != "" {
+		oldContent, err = yamlToJson(oldYaml.(string))
 		if err != nil {
-			return err
+			return "", "", err
 		}
 	}
-	rt1, _ := r1m["resourceType"]
-	rt2, _ := r2m["resourceType"]
-	if rt1 != rt2 {
-		err = rd.ForceNew("resource")
+	if newYaml.(string) != "" {
+		newContent, err = yamlToJson(newYaml.(string))
 		if err != nil {
-			return err
+			return "", "", err
 		}
 	}
+	return oldContent, newContent, nil
+}
+
+// validateAidboxResource fails the plan if the server finds any errors with the resource, so that these don't turn up
+// only halfway through an apply
+func validateAidboxResource(ctx context.Context, resource string, apiClient *aidbox.ApiClient) error {
+	outcome, err := apiClient.ValidateResource(ctx, json.RawMessage(resource))
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for _, issue := range outcome.Issue {
+		problem := issue.Severity + ": " + issue.Diagnostics
+		if issue.Diagnostics == "" {
+			problem = issue.Severity + ": " + issue.Code
+		}
+		if len(issue.Expression) > 0 {
+			problem += " (at " + strings.Join(issue.Expression, ", ") + ")"
+		}
+		if issue.Severity == "fatal" || issue.Severity == "error" {
+			problems = append(problems, problem)
+		} else {
+			tflog.Warn(ctx, "Validating resource: "+problem)
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("resource failed validation:\n- %s", strings.Join(problems, "\n- "))
+	}
 	return nil
 }
 
 func mapAidboxResourceToData(res *aidbox.GenericResource, data *schema.ResourceData) error {
 	data.SetId(res.ResourceTypeAndId)
-	// filter the id/meta out here
-	var h map[string]any
-	err := json.Unmarshal(res.ResourceContent, &h)
+	// the metadata is only exposed through the computed attributes, it's filtered out of the content below
+	mapResourceMetaToData(res.Meta, data)
+	resourceContent, err := resourceContentForState(res.ResourceContent, data.Get("id_assigned").(bool))
+	if err != nil {
+		return err
+	}
+	previousContent := data.Get("resource").(string)
+	previousYaml := data.Get("resource_yaml").(string)
+	if previousYaml != "" {
+		previousContent, err = yamlToJson(previousYaml)
+		if err != nil {
+			return err
+		}
+	}
+	ignorePaths := toStrings(data.Get("ignore_paths"))
+	subsetMatch := data.Get("subset_match").(bool)
+	resourceContent, err = pruneAidboxResourceContent(resourceContent, previousContent, ignorePaths, subsetMatch)
+	if err != nil {
+		return err
+	}
+	if previousYaml == "" {
+		return data.Set("resource", resourceContent)
+	}
+	// keep the YAML as it was written, with its comments and field order, unless the resource changed
+	var actual, previous interface{}
+	if json.Unmarshal([]byte(resourceContent), &actual) == nil && json.Unmarshal([]byte(previousContent), &previous) == nil &&
+		jsonMatches(actual, previous, ignorePaths, subsetMatch) {
+		return data.Set("resource_yaml", previousYaml)
+	}
+	resourceYaml, err := jsonToYaml(resourceContent)
 	if err != nil {
 		return err
 	}
+	return data.Set("resource_yaml", resourceYaml)
+}
+
+// resourceContentForState filters the meta out of the content read from the server, and the id unless it was assigned
+// in the configuration, so that it can be compared with the configuration
+func resourceContentForState(content json.RawMessage, idAssigned bool) (string, error) {
+	var h map[string]any
+	err := json.Unmarshal(content, &h)
+	if err != nil {
+		return "", err
+	}
 	delete(h, "meta")
-	if !data.Get("id_assigned").(bool) {
+	if !idAssigned {
 		delete(h, "id")
 	}
 	resourceContent, err := json.Marshal(h)
 	if err != nil {
-		return err
+		return "", err
 	}
-	err = data.Set("resource", string(resourceContent))
+	return string(resourceContent), nil
+}
+
+// pruneAidboxResourceContent drops the ignored paths from the content read from the server, and with subsetMatch
+// whatever isn't in the previous content either, so that only what's managed by terraform is kept in the state
+func pruneAidboxResourceContent(content string, previousContent string, ignorePaths []string, subsetMatch bool) (string, error) {
+	var h interface{}
+	err := json.Unmarshal([]byte(content), &h)
 	if err != nil {
-		return err
+		return "", err
 	}
-	return nil
+	err = removeJsonPaths(h, ignorePaths)
+	if err != nil {
+		return "", err
+	}
+	if subsetMatch && previousContent != "" {
+		var previous interface{}
+		err = json.Unmarshal([]byte(previousContent), &previous)
+		if err != nil {
+			return "", err
+		}
+		err = removeJsonPaths(previous, ignorePaths)
+		if err != nil {
+			return "", err
+		}
+		h = jsonSubset(h, previous)
+	}
+	pruned, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	return string(pruned), nil
+}
+
+// aidboxResourceDiffSuppressFunc compares the resources like jsonDiffSuppressFunc does, but leaves out the
+// ignore_paths, and with subset_match everything the configuration doesn't have
+func aidboxResourceDiffSuppressFunc(k string, oldJson string, newJson string, d *schema.ResourceData) bool {
+	if oldJson == "" || newJson == "" || d == nil {
+		return oldJson == newJson
+	}
+	var oldObject interface{}
+	var newObject interface{}
+	if json.Unmarshal([]byte(oldJson), &oldObject) != nil || json.Unmarshal([]byte(newJson), &newObject) != nil {
+		return false
+	}
+	return jsonMatches(oldObject, newObject, toStrings(d.Get("ignore_paths")), d.Get("subset_match").(bool))
+}
+
+// aidboxResourceYamlDiffSuppressFunc compares the resources in YAML like aidboxResourceDiffSuppressFunc does in JSON
+func aidboxResourceYamlDiffSuppressFunc(k string, oldYaml string, newYaml string, d *schema.ResourceData) bool {
+	if oldYaml == "" || newYaml == "" || d == nil {
+		return oldYaml == newYaml
+	}
+	oldJson, err := yamlToJson(oldYaml)
+	if err != nil {
+		return false
+	}
+	newJson, err := yamlToJson(newYaml)
+	if err != nil {
+		return false
+	}
+	return aidboxResourceDiffSuppressFunc(k, oldJson, newJson, d)
+}
+
+func validateResourceYaml(v interface{}, k string) ([]string, []error) {
+	_, err := yamlToJson(v.(string))
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s must be a YAML mapping: %w", k, err)}
+	}
+	return nil, nil
 }
 
 func mapAidboxResourceFromData(d *schema.ResourceData) (*aidbox.GenericResource, error) {
 	res := &aidbox.GenericResource{}
 	res.ResourceTypeAndId = d.Id()
 	res.ResourceContent = []byte(d.Get("resource").(string))
+	if v, ok := d.GetOk("resource_yaml"); ok {
+		resourceContent, err := yamlToJson(v.(string))
+		if err != nil {
+			return nil, err
+		}
+		res.ResourceContent = []byte(resourceContent)
+		res.Yaml = v.(string)
+	}
+	if versionId := d.Get("version_id").(string); versionId != "" {
+		res.Meta = &aidbox.ResourceBaseMeta{VersionId: versionId}
+	}
+	res.ConditionalCreateQuery = d.Get("conditional_create_query").(string)
+	res.ConditionalUpdateQuery = d.Get("conditional_update_query").(string)
 	return res, nil
 }
 
@@ -93,7 +294,7 @@ func resourceAidboxResourceCreate(ctx context.Context, d *schema.ResourceData, m
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapAidboxResourceFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, nil)
 	}
 
 	// remember if we assigned the ID ourselves or if we accepted a server-set ID
@@ -102,17 +303,17 @@ func resourceAidboxResourceCreate(ctx context.Context, d *schema.ResourceData, m
 	if err == nil {
 		err = d.Set("id_assigned", true)
 		if err != nil {
-			return diag.FromErr(err)
+			return diagFromErr(err, nil)
 		}
 	}
 
 	res, err := apiClient.CreateGenericResource(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, nil)
 	}
 	err = mapAidboxResourceToData(res, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, nil)
 	}
 	return nil
 }
@@ -124,39 +325,97 @@ func resourceAidboxResourceRead(ctx context.Context, d *schema.ResourceData, met
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, nil)
 	}
 	err = mapAidboxResourceToData(res, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, nil)
 	}
 	return nil
 }
 
 func resourceAidboxResourceUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
+	if !d.HasChanges("resource", "resource_yaml") {
+		// only settings such as delete_mode changed, there's nothing to send
+		return nil
+	}
 	q, err := mapAidboxResourceFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, nil)
 	}
 	ti, err := apiClient.UpdateGenericResource(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, nil)
 	}
 	err = mapAidboxResourceToData(ti, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, nil)
 	}
 	return nil
 }
 
 func resourceAidboxResourceDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
-	err := apiClient.DeleteGenericResource(ctx, d.Id())
+	deleteMode := d.Get("delete_mode").(string)
+	if deleteMode == "abandon" {
+		log.Printf("[INFO] Leaving resource %s on the server as its delete_mode is abandon", d.Id())
+		return nil
+	}
+	var referencing []string
+	if d.Get("cascade_delete").(bool) {
+		var err error
+		referencing, err = apiClient.FindReferencingResources(ctx, d.Id())
+		if err != nil {
+			return diagFromErr(err, nil)
+		}
+	}
+
+	var diags diag.Diagnostics
+	if len(referencing) > 0 {
+		diags = diag.Diagnostics{{
+			Severity: diag.Warning,
+			Summary:  fmt.Sprintf("Deleted %d resources referencing %s", len(referencing), d.Id()),
+			Detail:   strings.Join(referencing, "\n"),
+		}}
+	}
+	var err error
+	if deleteMode == "purge" {
+		// $purge can't be part of a transaction and only purges a patient and its compartment. Whatever else refers to
+		// the patient is deleted first in a transaction of its own, so that nothing is left dangling.
+		if len(referencing) > 0 {
+			_, err = apiClient.SubmitTransaction(ctx, deleteTransaction(referencing))
+			if err != nil {
+				return diagFromErr(err, nil)
+			}
+		}
+		err = apiClient.PurgeGenericResource(ctx, d.Id())
+		if err != nil {
+			// the referencing resources are gone nevertheless
+			return append(diagFromErr(err, nil), diags...)
+		}
+		return diags
+	}
+	if len(referencing) > 0 {
+		_, err = apiClient.SubmitTransaction(ctx, deleteTransaction(append(referencing, d.Id())))
+	} else {
+		err = apiClient.DeleteGenericResource(ctx, d.Id())
+	}
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, nil)
 	}
-	return nil
+	return diags
+}
+
+// deleteTransaction deletes the resources, either all or none of them
+func deleteTransaction(resourceTypeAndIds []string) []aidbox.BundleEntry {
+	var entries []aidbox.BundleEntry
+	for _, resourceTypeAndId := range resourceTypeAndIds {
+		entries = append(entries, aidbox.BundleEntry{
+			Request: &aidbox.BundleEntryRequest{Method: "DELETE", Url: resourceTypeAndId},
+		})
+	}
+	return entries
 }
 
 func resourceAidboxResourceImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
@@ -178,7 +437,78 @@ func resourceSchemaAidboxResource() map[string]*schema.Schema {
 			Description:      "Aidbox resource content in JSON format",
 			Type:             schema.TypeString,
 			Optional:         true,
-			DiffSuppressFunc: jsonDiffSuppressFunc,
+			DiffSuppressFunc: aidboxResourceDiffSuppressFunc,
+			ExactlyOneOf:     []string{"resource", "resource_yaml"},
+		},
+		"resource_yaml": {
+			Description: "Aidbox resource content in YAML format, sent to the server as it is. It's kept as written " +
+				"in the state, unless the resource changed on the server, which is then read back in YAML with the " +
+				"fields in alphabetical order.",
+			Type:             schema.TypeString,
+			Optional:         true,
+			DiffSuppressFunc: aidboxResourceYamlDiffSuppressFunc,
+			ValidateFunc:     validateResourceYaml,
+		},
+		"ignore_paths": {
+			Description: "Paths in the resource that are left out when comparing it with the server, for fields the server " +
+				"populates, such as `text` narratives. Either JSON pointers, e.g. `/text/div`, or dot separated, e.g. " +
+				"`identifier.0.use`. A `*` segment matches every array element or object field, e.g. `identifier.*.use`.",
+			Type:     schema.TypeList,
+			Optional: true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validateJsonPath,
+			},
+		},
+		"subset_match": {
+			Description: "Only compare the fields written in the resource with the server, anything else the server has " +
+				"is ignored. This also means that only removing a field from the resource doesn't cause an update.",
+			Type:     schema.TypeBool,
+			Optional: true,
+			Default:  false,
+		},
+		"validate_on_plan": {
+			Description: "Validate the resource with the server's `$validate` operation during plan, so that profile " +
+				"violations fail the plan rather than the apply",
+			Type:     schema.TypeBool,
+			Optional: true,
+			Default:  false,
+		},
+		"conditional_create_query": {
+			Description: "Search query, e.g. `identifier=http://example.com/ods|ABC`, that makes the resource be created " +
+				"only if nothing matches it. The existing resource is taken over otherwise, and updated on the next apply " +
+				"if it differs. Only used when the resource is created.",
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"conditional_update_query"},
+			ValidateFunc:  validateSearchQuery,
+		},
+		"conditional_update_query": {
+			Description: "Search query, e.g. `identifier=http://example.com/ods|ABC`, to find the resource to update " +
+				"when it's created, it's created if nothing matches it. Only used when the resource is created, it's " +
+				"updated by the ID recorded then afterwards.",
+			Type:          schema.TypeString,
+			Optional:      true,
+			ConflictsWith: []string{"conditional_create_query"},
+			ValidateFunc:  validateSearchQuery,
+		},
+		"delete_mode": {
+			Description: "What destroying the resource does: `delete` deletes it, keeping its history, `abandon` leaves " +
+				"it on the server, e.g. for seeded terminology, and `purge` removes it along with its history with the " +
+				"`$purge` operation, which aidbox only has for a Patient and removes the data in its compartment too.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			Default:      "delete",
+			ValidateFunc: validation.StringInSlice([]string{"delete", "abandon", "purge"}, false),
+		},
+		"cascade_delete": {
+			Description: "Also delete the resources referencing this one when it's destroyed, as found by " +
+				"`_revinclude=*:*`. Only direct references are followed. The deleted resources are reported as warnings. " +
+				"They're deleted in one transaction, together with the resource with `delete_mode` delete, or before the " +
+				"resource is purged with `delete_mode` purge.",
+			Type:     schema.TypeBool,
+			Optional: true,
+			Default:  false,
 		},
 		"id_assigned": {
 			Description: "Whether an ID was assigned in the original resource or not",
@@ -187,3 +517,16 @@ func resourceSchemaAidboxResource() map[string]*schema.Schema {
 		},
 	}
 }
+
+// validateSearchQuery checks the value is a non-empty query string, without the leading ?
+func validateSearchQuery(v interface{}, k string) ([]string, []error) {
+	query := v.(string)
+	if query == "" || strings.HasPrefix(query, "?") {
+		return nil, []error{fmt.Errorf("%s must be a search query without the leading ?, e.g. name=foo, got %q", k, query)}
+	}
+	_, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s must be a search query: %w", k, err)}
+	}
+	return nil, nil
+}
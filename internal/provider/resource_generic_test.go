@@ -1,10 +1,16 @@
 package provider
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestAccResourceAidboxResource(t *testing.T) {
@@ -17,6 +23,8 @@ func TestAccResourceAidboxResource(t *testing.T) {
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttr("aidbox_resource.my_resource", "id", "AidboxJob/my_aidbox_job"),
 					resource.TestCheckResourceAttrWith("aidbox_resource.my_resource", "resource", compIgnoreJsonDiff(my_resource)),
+					resource.TestCheckResourceAttrSet("aidbox_resource.my_resource", "version_id"),
+					resource.TestCheckResourceAttrSet("aidbox_resource.my_resource", "last_updated"),
 				),
 			},
 			{
@@ -24,6 +32,7 @@ func TestAccResourceAidboxResource(t *testing.T) {
 				Check: resource.ComposeTestCheckFunc(
 					resource.TestCheckResourceAttr("aidbox_resource.my_resource", "id", "AidboxJob/my_aidbox_job"),
 					resource.TestCheckResourceAttrWith("aidbox_resource.my_resource", "resource", compIgnoreJsonDiff(my_resource_updated)),
+					resource.TestCheckResourceAttrSet("aidbox_resource.my_resource", "version_id"),
 				),
 			},
 			{
@@ -185,3 +194,354 @@ const my_resource_noid_updated_addid = `
   "resourceType": "AidboxConfig"
 }
 `
+
+func TestAccResourceAidboxResource_validateOnPlan(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { requireSchemaMode(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceAidboxResource_validateOnPlan_invalid,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("resource failed validation"),
+			},
+			{
+				Config: testAccResourceAidboxResource_validateOnPlan_valid,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_resource.validated_patient", "id", "Patient/validated-patient"),
+				),
+			},
+		},
+	})
+}
+
+const testAccResourceAidboxResource_validateOnPlan_invalid = `
+resource "aidbox_resource" "validated_patient" {
+  validate_on_plan = true
+  resource = jsonencode({
+    resourceType = "Patient"
+    id           = "validated-patient"
+    gender       = "troll"
+  })
+}
+`
+
+const testAccResourceAidboxResource_validateOnPlan_valid = `
+resource "aidbox_resource" "validated_patient" {
+  validate_on_plan = true
+  resource = jsonencode({
+    resourceType = "Patient"
+    id           = "validated-patient"
+    gender       = "male"
+  })
+}
+`
+
+func TestAccResourceAidboxResource_conditional(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceAidboxResource_conditional_withId,
+				PlanOnly:    true,
+				ExpectError: regexp.MustCompile("can only be used with resources without an id"),
+			},
+			{
+				Config: testAccResourceAidboxResource_conditional,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_resource.updated_organization", "id_assigned", "false"),
+					// the second one finds the organization created by the first rather than creating another
+					resource.TestCheckResourceAttrPair("aidbox_resource.updated_organization", "id", "aidbox_resource.created_organization", "id"),
+				),
+			},
+		},
+	})
+}
+
+const testAccResourceAidboxResource_conditional_withId = `
+resource "aidbox_resource" "created_organization" {
+  conditional_create_query = "identifier=http://example.com/ods|TF-ACC-1"
+  resource = jsonencode({
+    resourceType = "Organization"
+    id           = "tf-acc-1"
+  })
+}
+`
+
+const testAccResourceAidboxResource_conditional = `
+resource "aidbox_resource" "updated_organization" {
+  conditional_update_query = "identifier=http://example.com/ods|TF-ACC-1"
+  resource = jsonencode({
+    resourceType = "Organization"
+    name         = "Terraform acceptance test"
+    identifier   = [{ system = "http://example.com/ods", value = "TF-ACC-1" }]
+  })
+}
+
+resource "aidbox_resource" "created_organization" {
+  conditional_create_query = "identifier=http://example.com/ods|TF-ACC-1"
+  resource = jsonencode({
+    resourceType = "Organization"
+    name         = "Terraform acceptance test"
+    identifier   = [{ system = "http://example.com/ods", value = "TF-ACC-1" }]
+  })
+  depends_on = [aidbox_resource.updated_organization]
+}
+`
+
+func TestValidateSearchQuery(t *testing.T) {
+	_, errs := validateSearchQuery("identifier=http://example.com/ods|ABC&active=true", "conditional_create_query")
+	assert.Empty(t, errs)
+
+	_, errs = validateSearchQuery("?name=foo", "conditional_create_query")
+	assert.Len(t, errs, 1)
+
+	_, errs = validateSearchQuery("name=%zz", "conditional_create_query")
+	assert.Len(t, errs, 1)
+}
+
+func TestAccResourceAidboxResource_ignorePaths(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceAidboxResource_ignorePaths,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttrWith("aidbox_resource.ignoring", "resource", compIgnoreJsonDiff(`{"resourceType": "Patient", "id": "ignoring-patient", "active": true}`)),
+				),
+			},
+			{
+				// the gender is ignored and the missing active field isn't compared
+				Config:   testAccResourceAidboxResource_ignorePaths_changed,
+				PlanOnly: true,
+			},
+		},
+	})
+}
+
+const testAccResourceAidboxResource_ignorePaths = `
+resource "aidbox_resource" "ignoring" {
+  ignore_paths = ["gender"]
+  subset_match = true
+  resource = jsonencode({
+    resourceType = "Patient"
+    id           = "ignoring-patient"
+    active       = true
+    gender       = "female"
+  })
+}
+`
+
+const testAccResourceAidboxResource_ignorePaths_changed = `
+resource "aidbox_resource" "ignoring" {
+  ignore_paths = ["gender"]
+  subset_match = true
+  resource = jsonencode({
+    resourceType = "Patient"
+    id           = "ignoring-patient"
+    gender       = "male"
+  })
+}
+`
+
+func TestAccResourceAidboxResource_yaml(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceAidboxResource_yaml,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_resource.yaml_patient", "id", "Patient/yaml-patient"),
+					// kept as written
+					resource.TestCheckResourceAttr("aidbox_resource.yaml_patient", "resource_yaml", yamlPatient),
+					resource.TestCheckResourceAttr("aidbox_resource.yaml_patient", "resource", ""),
+				),
+			},
+			{
+				Config: testAccResourceAidboxResource_yaml_json,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_resource.yaml_patient", "id", "Patient/yaml-patient"),
+					resource.TestCheckResourceAttrWith("aidbox_resource.yaml_patient", "resource", compIgnoreJsonDiff(`{"resourceType": "Patient", "id": "yaml-patient", "birthDate": "2020-01-01"}`)),
+					resource.TestCheckResourceAttr("aidbox_resource.yaml_patient", "resource_yaml", ""),
+				),
+			},
+		},
+	})
+}
+
+const yamlPatient = `# a patient written in YAML
+resourceType: Patient
+id: yaml-patient
+birthDate: 2020-01-01
+`
+
+const testAccResourceAidboxResource_yaml = `
+resource "aidbox_resource" "yaml_patient" {
+  resource_yaml = <<-EOT
+` + yamlPatient + `EOT
+}
+`
+
+const testAccResourceAidboxResource_yaml_json = `
+resource "aidbox_resource" "yaml_patient" {
+  resource = jsonencode({
+    resourceType = "Patient"
+    id           = "yaml-patient"
+    birthDate    = "2020-01-01"
+  })
+}
+`
+
+func TestAccResourceAidboxResource_abandon(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceAidboxResource_abandon,
+			},
+		},
+		CheckDestroy: func(state *terraform.State) error {
+			apiClient := testProvider.Meta().(*aidbox.ApiClient)
+			_, err := apiClient.GetGenericResource(context.Background(), "ValueSet/abandoned-value-set")
+			if err != nil {
+				return fmt.Errorf("abandoned ValueSet should still exist: %w", err)
+			}
+			return apiClient.DeleteGenericResource(context.Background(), "ValueSet/abandoned-value-set")
+		},
+	})
+}
+
+const testAccResourceAidboxResource_abandon = `
+resource "aidbox_resource" "abandoned" {
+  delete_mode = "abandon"
+  resource = jsonencode({
+    resourceType = "ValueSet"
+    id           = "abandoned-value-set"
+    status       = "active"
+  })
+}
+`
+
+func TestAccResourceAidboxResource_cascadeDelete(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				PreConfig: func() {
+					// created outside of terraform, so that only the cascade deletes it
+					apiClient := testProvider.Meta().(*aidbox.ApiClient)
+					_, err := apiClient.CreateGenericResource(context.Background(), &aidbox.GenericResource{
+						ResourceContent: []byte(`{"resourceType": "Observation", "id": "cascaded-observation", "status": "final",
+							"code": {"text": "weight"}, "subject": {"reference": "Patient/cascading-patient"}}`),
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: testAccResourceAidboxResource_cascadeDelete,
+				// aidbox has to honour the wildcard for the cascade to find anything
+				Check: testAccCheckReferencedBy("Patient/cascading-patient", "Observation/cascaded-observation"),
+			},
+		},
+		CheckDestroy: func(state *terraform.State) error {
+			apiClient := testProvider.Meta().(*aidbox.ApiClient)
+			for _, resourceTypeAndId := range []string{"Patient/cascading-patient", "Observation/cascaded-observation"} {
+				_, err := apiClient.GetGenericResource(context.Background(), resourceTypeAndId)
+				if !errors.Is(err, aidbox.NotFoundError) {
+					return fmt.Errorf("%s should have been deleted: %v", resourceTypeAndId, err)
+				}
+			}
+			return nil
+		},
+	})
+}
+
+func testAccCheckReferencedBy(resourceTypeAndId string, referencing string) resource.TestCheckFunc {
+	return func(state *terraform.State) error {
+		apiClient := testProvider.Meta().(*aidbox.ApiClient)
+		found, err := apiClient.FindReferencingResources(context.Background(), resourceTypeAndId)
+		if err != nil {
+			return err
+		}
+		for _, r := range found {
+			if r == referencing {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s wasn't found to reference %s, found %v", referencing, resourceTypeAndId, found)
+	}
+}
+
+func TestAccResourceAidboxResource_purge(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config:      testAccResourceAidboxResource_purgeValueSet,
+				ExpectError: regexp.MustCompile("delete_mode purge can't be used for a ValueSet"),
+			},
+			{
+				PreConfig: func() {
+					apiClient := testProvider.Meta().(*aidbox.ApiClient)
+					_, err := apiClient.CreateGenericResource(context.Background(), &aidbox.GenericResource{
+						ResourceContent: []byte(`{"resourceType": "Observation", "id": "purged-observation", "status": "final",
+							"code": {"text": "weight"}, "subject": {"reference": "Patient/purged-patient"}}`),
+					})
+					if err != nil {
+						t.Fatal(err)
+					}
+				},
+				Config: testAccResourceAidboxResource_purge,
+				Check:  testAccCheckReferencedBy("Patient/purged-patient", "Observation/purged-observation"),
+			},
+		},
+		CheckDestroy: func(state *terraform.State) error {
+			apiClient := testProvider.Meta().(*aidbox.ApiClient)
+			for _, resourceTypeAndId := range []string{"Patient/purged-patient", "Observation/purged-observation"} {
+				_, err := apiClient.GetGenericResource(context.Background(), resourceTypeAndId)
+				if !errors.Is(err, aidbox.NotFoundError) {
+					return fmt.Errorf("%s should have been removed: %v", resourceTypeAndId, err)
+				}
+			}
+			return nil
+		},
+	})
+}
+
+const testAccResourceAidboxResource_purgeValueSet = `
+resource "aidbox_resource" "purged" {
+  delete_mode = "purge"
+  resource = jsonencode({
+    resourceType = "ValueSet"
+    id           = "purged-value-set"
+    status       = "active"
+  })
+}
+`
+
+const testAccResourceAidboxResource_purge = `
+resource "aidbox_resource" "purged" {
+  delete_mode    = "purge"
+  cascade_delete = true
+  resource = jsonencode({
+    resourceType = "Patient"
+    id           = "purged-patient"
+  })
+}
+`
+
+const testAccResourceAidboxResource_cascadeDelete = `
+resource "aidbox_resource" "cascading" {
+  cascade_delete = true
+  resource = jsonencode({
+    resourceType = "Patient"
+    id           = "cascading-patient"
+  })
+}
+`
@@ -2,10 +2,11 @@ package provider
 
 import (
 	"context"
-	"log"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
 )
 
@@ -66,9 +67,10 @@ func resourceSchemaIdentityProvider() map[string]*schema.Schema {
 			Optional:    true,
 		},
 		"userinfo_source": {
-			Description: "One of (id-token|userinfo-endpoint). If `id-token`, then `user.data` is populated with the `id_token.claims` value. Otherwise request to the `userinfo_endpoint` is performed to get user details.",
-			Type:        schema.TypeString,
-			Optional:    true,
+			Description:  "One of (id-token|userinfo-endpoint). If `id-token`, then `user.data` is populated with the `id_token.claims` value. Otherwise request to the `userinfo_endpoint` is performed to get user details.",
+			Type:         schema.TypeString,
+			Optional:     true,
+			ValidateFunc: validation.StringInSlice(aidbox.UserinfoSources.Strings(), false),
 		},
 		"userinfo_endpoint": {
 			Description: "OAuth Provider user profile endpoint.",
@@ -85,14 +87,14 @@ func resourceSchemaIdentityProvider() map[string]*schema.Schema {
 		},
 	}
 }
-func mapIdentityProviderToData(v *aidbox.IdentityProvider, data *schema.ResourceData) {
+func mapIdentityProviderToData(v *aidbox.IdentityProvider, data *schema.ResourceData) diag.Diagnostics {
 	mapResourceBaseToData(&v.ResourceBase, data)
 
 	data.Set("title", v.Title)
 	data.Set("system", v.System)
 	data.Set("authorize_endpoint", v.AuthorizeEndpoint)
 	data.Set("token_endpoint", v.TokenEndpoint)
-	data.Set("userinfo_source", v.UserinfoSource.ToString())
+	data.Set("userinfo_source", string(v.UserinfoSource))
 	data.Set("userinfo_endpoint", v.UserinfoEndpoint)
 	data.Set("scopes", v.Scopes)
 
@@ -103,6 +105,7 @@ func mapIdentityProviderToData(v *aidbox.IdentityProvider, data *schema.Resource
 		}
 		data.Set("client", []interface{}{client})
 	}
+	return unknownEnumWarning(cty.GetAttrPath("userinfo_source"), v.UserinfoSource)
 }
 
 func mapIdentityProviderFromData(d *schema.ResourceData) *aidbox.IdentityProvider {
@@ -121,11 +124,7 @@ func mapIdentityProviderFromData(d *schema.ResourceData) *aidbox.IdentityProvide
 		vv.Scopes = append(vv.Scopes, scope.(string))
 	}
 
-	if tt, err := aidbox.ParseUserinfoSource(d.Get("userinfo_source").(string)); err == nil {
-		vv.UserinfoSource = tt
-	} else {
-		log.Panicln(err)
-	}
+	vv.UserinfoSource = aidbox.IdentityProviderUserinfoSource(d.Get("userinfo_source").(string))
 
 	if v, ok := d.GetOk("client"); ok {
 		clientData := v.([]interface{})[0].(map[string]interface{}) // Ugly
@@ -143,10 +142,9 @@ func resourceIdentityProviderCreate(ctx context.Context, d *schema.ResourceData,
 	q := mapIdentityProviderFromData(d)
 	res, err := apiClient.CreateIdentityProvider(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaIdentityProvider())
 	}
-	mapIdentityProviderToData(res, d)
-	return nil
+	return mapIdentityProviderToData(res, d)
 }
 
 func resourceIdentityProviderRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -157,10 +155,9 @@ func resourceIdentityProviderRead(ctx context.Context, d *schema.ResourceData, m
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaIdentityProvider())
 	}
-	mapIdentityProviderToData(res, d)
-	return nil
+	return mapIdentityProviderToData(res, d)
 }
 
 func resourceIdentityProviderUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -168,17 +165,16 @@ func resourceIdentityProviderUpdate(ctx context.Context, d *schema.ResourceData,
 	q := mapIdentityProviderFromData(d)
 	ti, err := apiClient.UpdateIdentityProvider(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaIdentityProvider())
 	}
-	mapIdentityProviderToData(ti, d)
-	return nil
+	return mapIdentityProviderToData(ti, d)
 }
 
 func resourceIdentityProviderDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteIdentityProvider(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaIdentityProvider())
 	}
 	return nil
 }
@@ -189,6 +185,8 @@ func resourceIdentityProviderImport(ctx context.Context, d *schema.ResourceData,
 	if err != nil {
 		return nil, err
 	}
-	mapIdentityProviderToData(res, d)
+	if err := logImportDiagnostics(ctx, mapIdentityProviderToData(res, d)); err != nil {
+		return nil, err
+	}
 	return []*schema.ResourceData{d}, nil
 }
@@ -44,7 +44,7 @@ func resourceSchemaQuestionnaireTheme() map[string]*schema.Schema {
 }
 
 func mapQuestionnaireThemeToData(res *aidbox.QuestionnaireTheme, data *schema.ResourceData) error {
-	data.SetId(res.ID)
+	mapResourceBaseToData(&res.ResourceBase, data)
 	if err := data.Set("aidbox_id", res.ID); err != nil {
 		return err
 	}
@@ -58,7 +58,7 @@ func mapQuestionnaireThemeToData(res *aidbox.QuestionnaireTheme, data *schema.Re
 }
 
 func mapQuestionnaireThemeFromData(d *schema.ResourceData) *aidbox.QuestionnaireTheme {
-	res := &aidbox.QuestionnaireTheme{}
+	res := &aidbox.QuestionnaireTheme{ResourceBase: mapResourceBaseFromData(d)}
 	if v, ok := d.GetOk("aidbox_id"); ok {
 		res.ID = v.(string)
 	}
@@ -76,10 +76,10 @@ func resourceQuestionnaireThemeCreate(ctx context.Context, d *schema.ResourceDat
 	q := mapQuestionnaireThemeFromData(d)
 	res, err := apiClient.CreateQuestionnaireTheme(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaQuestionnaireTheme())
 	}
 	if err := mapQuestionnaireThemeToData(res, d); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaQuestionnaireTheme())
 	}
 	return nil
 }
@@ -91,10 +91,10 @@ func resourceQuestionnaireThemeRead(ctx context.Context, d *schema.ResourceData,
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaQuestionnaireTheme())
 	}
 	if err := mapQuestionnaireThemeToData(res, d); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaQuestionnaireTheme())
 	}
 	return nil
 }
@@ -104,10 +104,10 @@ func resourceQuestionnaireThemeUpdate(ctx context.Context, d *schema.ResourceDat
 	q := mapQuestionnaireThemeFromData(d)
 	res, err := apiClient.UpdateQuestionnaireTheme(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaQuestionnaireTheme())
 	}
 	if err := mapQuestionnaireThemeToData(res, d); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaQuestionnaireTheme())
 	}
 	return nil
 }
@@ -116,7 +116,7 @@ func resourceQuestionnaireThemeDelete(ctx context.Context, d *schema.ResourceDat
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteQuestionnaireTheme(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaQuestionnaireTheme())
 	}
 	return nil
 }
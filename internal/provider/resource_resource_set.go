@@ -0,0 +1,338 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/id"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+)
+
+// resourceSetParallelism is how many entries of a set are applied at the same time, on top of which the client's own
+// max_concurrent_requests still applies
+const resourceSetParallelism = 8
+
+// resourceSetSearchBatch is how many ids are looked up with one search when refreshing a set
+const resourceSetSearchBatch = 50
+
+func resourceResourceSet() *schema.Resource {
+	return &schema.Resource{
+		Description: "Set of resources loaded from `.json` and `.ndjson` files, each of which is keyed by its " +
+			"ResourceType/id, so every resource has to have an id. Only the entries whose content changed are sent, " +
+			"and entries removed from the files are deleted. An entry that fails doesn't stop the others, it's " +
+			"reported and retried on the next apply. Changes made to the resources on the server aren't detected, " +
+			"only resources that were deleted.",
+		CreateContext: resourceResourceSetCreate,
+		ReadContext:   resourceResourceSetRead,
+		UpdateContext: resourceResourceSetUpdate,
+		DeleteContext: resourceResourceSetDelete,
+		CustomizeDiff: customizeResourceSetDiff,
+		Schema:        resourceSchemaResourceSet(),
+	}
+}
+
+func resourceSchemaResourceSet() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"path": {
+			Description: "A directory, whose `.json` and `.ndjson` files are loaded including those in subdirectories, " +
+				"or a single `.json` or `.ndjson` file",
+			Type:     schema.TypeString,
+			Required: true,
+		},
+		"entries": {
+			Description: "SHA-256 hash of the content of every resource applied, by ResourceType/id",
+			Type:        schema.TypeMap,
+			Computed:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+	}
+}
+
+// loadResourceSet reads every resource from the path, by ResourceType/id. The problems with every file are reported
+// together.
+func loadResourceSet(root string) (map[string]json.RawMessage, error) {
+	resources := map[string]json.RawMessage{}
+	var problems []string
+	add := func(location string, content []byte) {
+		key, err := aidbox.GetResourceTypeAndId(content)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %s", location, err))
+			return
+		}
+		if _, ok := resources[key]; ok {
+			problems = append(problems, fmt.Sprintf("%s: %s is in the set more than once", location, key))
+			return
+		}
+		resources[key] = content
+	}
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".json":
+			content, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			add(path, content)
+		case ".ndjson":
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			scanner := bufio.NewScanner(file)
+			scanner.Buffer(nil, 64*1024*1024)
+			for line := 1; scanner.Scan(); line++ {
+				content := bytes.TrimSpace(scanner.Bytes())
+				if len(content) > 0 {
+					add(fmt.Sprintf("%s:%d", path, line), bytes.Clone(content))
+				}
+			}
+			return scanner.Err()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, fmt.Errorf("couldn't load the resources:\n- %s", strings.Join(problems, "\n- "))
+	}
+	return resources, nil
+}
+
+// resourceHash is the SHA-256 of the content, which only changes when the content does, not its formatting
+func resourceHash(content json.RawMessage) (string, error) {
+	var h interface{}
+	err := json.Unmarshal(content, &h)
+	if err != nil {
+		return "", err
+	}
+	canonical, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(canonical)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func resourceSetHashes(resources map[string]json.RawMessage) (map[string]string, error) {
+	hashes := map[string]string{}
+	for key, content := range resources {
+		hash, err := resourceHash(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		hashes[key] = hash
+	}
+	return hashes, nil
+}
+
+// resourceSetChange is an entry of the set to put, or to delete if it has no content
+type resourceSetChange struct {
+	Key     string
+	Content json.RawMessage
+	Hash    string
+}
+
+// planResourceSetChanges lists the entries to put because they're new or their hash differs, and the entries to delete
+// because they're no longer in the set, in the order of their keys
+func planResourceSetChanges(oldHashes map[string]string, resources map[string]json.RawMessage, hashes map[string]string) []resourceSetChange {
+	var changes []resourceSetChange
+	for key, hash := range hashes {
+		if oldHashes[key] != hash {
+			changes = append(changes, resourceSetChange{Key: key, Content: resources[key], Hash: hash})
+		}
+	}
+	for key := range oldHashes {
+		if _, ok := hashes[key]; !ok {
+			changes = append(changes, resourceSetChange{Key: key})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// applyResourceSetChanges applies the changes independently of each other, recording the entries that succeeded and
+// reporting an error for every one that failed
+func applyResourceSetChanges(ctx context.Context, apiClient *aidbox.ApiClient, changes []resourceSetChange, oldHashes map[string]string, d *schema.ResourceData) diag.Diagnostics {
+	failures := make([]error, len(changes))
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, resourceSetParallelism)
+	for i, change := range changes {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if change.Content == nil {
+				err := apiClient.DeleteGenericResource(ctx, change.Key)
+				if err != nil && !errors.Is(err, aidbox.NotFoundError) {
+					failures[i] = err
+				}
+				return
+			}
+			_, failures[i] = apiClient.CreateGenericResource(ctx, &aidbox.GenericResource{
+				ResourceTypeAndId: change.Key,
+				ResourceContent:   change.Content,
+			})
+		}()
+	}
+	wg.Wait()
+
+	entries := map[string]interface{}{}
+	for key, hash := range oldHashes {
+		entries[key] = hash
+	}
+	var diags diag.Diagnostics
+	for i, change := range changes {
+		if failures[i] != nil {
+			diags = append(diags, diag.Diagnostic{
+				Severity: diag.Error,
+				Summary:  fmt.Sprintf("Failed to apply %s", change.Key),
+				Detail:   failures[i].Error(),
+			})
+			continue
+		}
+		if change.Content == nil {
+			delete(entries, change.Key)
+		} else {
+			entries[change.Key] = change.Hash
+		}
+	}
+	err := d.Set("entries", entries)
+	if err != nil {
+		return append(diags, diag.FromErr(err)...)
+	}
+	return diags
+}
+
+func customizeResourceSetDiff(ctx context.Context, rd *schema.ResourceDiff, meta interface{}) error {
+	if !rd.NewValueKnown("path") {
+		return rd.SetNewComputed("entries")
+	}
+	resources, err := loadResourceSet(rd.Get("path").(string))
+	if err != nil {
+		return err
+	}
+	hashes, err := resourceSetHashes(resources)
+	if err != nil {
+		return err
+	}
+	if len(planResourceSetChanges(toStringMap(rd.Get("entries")), resources, hashes)) == 0 {
+		return nil
+	}
+	return rd.SetNew("entries", hashes)
+}
+
+func resourceResourceSetApply(ctx context.Context, d *schema.ResourceData, meta interface{}, oldHashes map[string]string) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	resources, err := loadResourceSet(d.Get("path").(string))
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	hashes, err := resourceSetHashes(resources)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return applyResourceSetChanges(ctx, apiClient, planResourceSetChanges(oldHashes, resources, hashes), oldHashes, d)
+}
+
+func resourceResourceSetCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	// not the path, which can change without replacing the set
+	d.SetId(id.UniqueId())
+	diags := resourceResourceSetApply(ctx, d, meta, map[string]string{})
+	if !diags.HasError() || len(d.Get("entries").(map[string]interface{})) == 0 {
+		return diags
+	}
+	// an error would taint the set, and replacing it would delete the entries that succeeded. Those that failed aren't
+	// in the state, so they're retried on the next apply like after a failed update.
+	for i := range diags {
+		diags[i].Severity = diag.Warning
+	}
+	return diags
+}
+
+// resourceResourceSetRead drops the entries that no longer exist from the state, so that they're created again
+func resourceResourceSetRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	entries := toStringMap(d.Get("entries"))
+	idsByType := map[string][]string{}
+	for key := range entries {
+		resourceType, id, _ := strings.Cut(key, "/")
+		idsByType[resourceType] = append(idsByType[resourceType], id)
+	}
+	existing := map[string]bool{}
+	for resourceType, ids := range idsByType {
+		sort.Strings(ids)
+		for start := 0; start < len(ids); start += resourceSetSearchBatch {
+			batch := ids[start:min(start+resourceSetSearchBatch, len(ids))]
+			found, err := apiClient.SearchResources(ctx, resourceType, url.Values{
+				"_id":       {strings.Join(batch, ",")},
+				"_elements": {"id"},
+				"_count":    {fmt.Sprint(len(batch))},
+			})
+			if err != nil {
+				return diagFromErr(err, nil)
+			}
+			for _, resource := range found {
+				key, err := aidbox.GetResourceTypeAndId(resource)
+				if err != nil {
+					return diag.FromErr(err)
+				}
+				existing[key] = true
+			}
+		}
+	}
+	refreshed := map[string]interface{}{}
+	for key, hash := range entries {
+		if existing[key] {
+			refreshed[key] = hash
+		}
+	}
+	err := d.Set("entries", refreshed)
+	if err != nil {
+		return diag.FromErr(err)
+	}
+	return nil
+}
+
+func resourceResourceSetUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	oldEntries, _ := d.GetChange("entries")
+	return resourceResourceSetApply(ctx, d, meta, toStringMap(oldEntries))
+}
+
+func resourceResourceSetDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	oldHashes := toStringMap(d.Get("entries"))
+	diags := applyResourceSetChanges(ctx, apiClient, planResourceSetChanges(oldHashes, nil, nil), oldHashes, d)
+	if diags.HasError() {
+		return diags
+	}
+	return nil
+}
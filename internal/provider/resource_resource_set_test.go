@@ -0,0 +1,226 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccResourceResourceSet(t *testing.T) {
+	// terraform runs in a temporary directory
+	dir, err := filepath.Abs("test_resources/resource_set")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var setId string
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: fmt.Sprintf(testAccResourceResourceSet, dir),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_resource_set.seed", "entries.%", "3"),
+					resource.TestCheckResourceAttrSet("aidbox_resource_set.seed", "entries.ValueSet/resource-set-colours"),
+					resource.TestCheckResourceAttrSet("aidbox_resource_set.seed", "entries.Organization/resource-set-guild"),
+					resource.TestCheckResourceAttrSet("aidbox_resource_set.seed", "entries.Organization/resource-set-union"),
+					resource.TestCheckResourceAttrWith("aidbox_resource_set.seed", "id", func(value string) error {
+						setId = value
+						return nil
+					}),
+				),
+			},
+			{
+				// only the NDJSON file is left, the value set is deleted
+				Config: fmt.Sprintf(testAccResourceResourceSet_ndjson, dir),
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_resource_set.seed", "entries.%", "2"),
+					resource.TestCheckNoResourceAttr("aidbox_resource_set.seed", "entries.ValueSet/resource-set-colours"),
+					// the same set, although its path changed
+					resource.TestCheckResourceAttrPtr("aidbox_resource_set.seed", "id", &setId),
+				),
+			},
+		},
+	})
+}
+
+const testAccResourceResourceSet = `
+resource "aidbox_resource_set" "seed" {
+  path = "%s"
+}
+`
+
+const testAccResourceResourceSet_ndjson = `
+resource "aidbox_resource_set" "seed" {
+  path = "%s/organizations.ndjson"
+}
+`
+
+func writeFile(t *testing.T, path string, content string) {
+	assert.Equal(t, nil, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.Equal(t, nil, os.WriteFile(path, []byte(content), 0o644))
+}
+
+func TestLoadResourceSet(t *testing.T) {
+	t.Run("should load JSON and NDJSON files from the directory tree", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "a.json"), `{"resourceType": "ValueSet", "id": "a"}`)
+		writeFile(t, filepath.Join(dir, "nested", "b.JSON"), `{"resourceType": "ValueSet", "id": "b"}`)
+		writeFile(t, filepath.Join(dir, "c.ndjson"), "{\"resourceType\": \"Organization\", \"id\": \"c\"}\n\n{\"resourceType\": \"Organization\", \"id\": \"d\"}\n")
+		writeFile(t, filepath.Join(dir, "notes.md"), "ignored")
+
+		resources, err := loadResourceSet(dir)
+
+		assert.Equal(t, nil, err)
+		assert.Equal(t, map[string]json.RawMessage{
+			"ValueSet/a":     []byte(`{"resourceType": "ValueSet", "id": "a"}`),
+			"ValueSet/b":     []byte(`{"resourceType": "ValueSet", "id": "b"}`),
+			"Organization/c": []byte(`{"resourceType": "Organization", "id": "c"}`),
+			"Organization/d": []byte(`{"resourceType": "Organization", "id": "d"}`),
+		}, resources)
+	})
+
+	t.Run("should load a single file", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "c.ndjson"), `{"resourceType": "Organization", "id": "c"}`)
+
+		resources, err := loadResourceSet(filepath.Join(dir, "c.ndjson"))
+
+		assert.Equal(t, nil, err)
+		assert.Len(t, resources, 1)
+	})
+
+	t.Run("should report every problem", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "a.json"), `{"resourceType": "ValueSet"}`)
+		writeFile(t, filepath.Join(dir, "b.ndjson"), "{\"resourceType\": \"ValueSet\", \"id\": \"c\"}\n{\"resourceType\": \"ValueSet\", \"id\": \"c\"}\n")
+		writeFile(t, filepath.Join(dir, "d.ndjson"), `{"resourceType": "Patient", "id": 5}`)
+
+		_, err := loadResourceSet(dir)
+
+		assert.ErrorContains(t, err, "a.json: no 'id' field in JSON body")
+		assert.ErrorContains(t, err, "b.ndjson:2: ValueSet/c is in the set more than once")
+		assert.ErrorContains(t, err, "d.ndjson:1: 'id' must be a string")
+	})
+
+	t.Run("should fail if the path doesn't exist", func(t *testing.T) {
+		_, err := loadResourceSet(filepath.Join(t.TempDir(), "missing"))
+
+		assert.Error(t, err)
+	})
+}
+
+func TestResourceHash(t *testing.T) {
+	hash, err := resourceHash([]byte(`{"resourceType": "ValueSet", "id": "a"}`))
+	assert.Equal(t, nil, err)
+	reformatted, err := resourceHash([]byte("{\n  \"id\": \"a\",\n  \"resourceType\": \"ValueSet\"\n}"))
+	assert.Equal(t, nil, err)
+	changed, err := resourceHash([]byte(`{"resourceType": "ValueSet", "id": "a", "status": "active"}`))
+	assert.Equal(t, nil, err)
+
+	assert.Equal(t, hash, reformatted)
+	assert.NotEqual(t, hash, changed)
+}
+
+func TestPlanResourceSetChanges(t *testing.T) {
+	resources := map[string]json.RawMessage{
+		"ValueSet/kept":    []byte(`{}`),
+		"ValueSet/changed": []byte(`{"status": "active"}`),
+		"ValueSet/new":     []byte(`{"status": "draft"}`),
+	}
+
+	changes := planResourceSetChanges(
+		map[string]string{"ValueSet/kept": "1", "ValueSet/changed": "2", "ValueSet/removed": "3"},
+		resources,
+		map[string]string{"ValueSet/kept": "1", "ValueSet/changed": "4", "ValueSet/new": "5"},
+	)
+
+	assert.Equal(t, []resourceSetChange{
+		{Key: "ValueSet/changed", Content: resources["ValueSet/changed"], Hash: "4"},
+		{Key: "ValueSet/new", Content: resources["ValueSet/new"], Hash: "5"},
+		{Key: "ValueSet/removed"},
+	}, changes)
+}
+
+func TestApplyResourceSetChanges(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ValueSet/broken":
+			w.WriteHeader(422)
+			w.Write([]byte(`{"resourceType": "OperationOutcome", "issue": [{"severity": "error", "code": "invalid"}]}`))
+		case "/ValueSet/gone":
+			w.WriteHeader(404)
+		default:
+			w.WriteHeader(200)
+			w.Write([]byte(`{"resourceType": "ValueSet", "id": "x"}`))
+		}
+	}))
+	defer server.Close()
+	apiClient := aidbox.NewApiClient(server.URL, "foo", "bar")
+	d := schema.TestResourceDataRaw(t, resourceSchemaResourceSet(), map[string]interface{}{"path": "seed"})
+
+	diags := applyResourceSetChanges(context.TODO(), apiClient, []resourceSetChange{
+		{Key: "ValueSet/broken", Content: []byte(`{"resourceType": "ValueSet", "id": "broken"}`), Hash: "new-broken"},
+		{Key: "ValueSet/gone"},
+		{Key: "ValueSet/new", Content: []byte(`{"resourceType": "ValueSet", "id": "new"}`), Hash: "new"},
+		{Key: "ValueSet/removed"},
+	}, map[string]string{"ValueSet/broken": "old-broken", "ValueSet/gone": "gone", "ValueSet/removed": "removed"}, d)
+
+	assert.Len(t, diags, 1)
+	assert.Equal(t, "Failed to apply ValueSet/broken", diags[0].Summary)
+	// the failed entry keeps its old hash so that it's retried
+	assert.Equal(t, map[string]interface{}{"ValueSet/broken": "old-broken", "ValueSet/new": "new"}, d.Get("entries"))
+}
+
+func TestResourceResourceSetCreate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/ValueSet/broken" {
+			w.WriteHeader(422)
+			w.Write([]byte(`{"resourceType": "OperationOutcome", "issue": [{"severity": "error", "code": "invalid"}]}`))
+			return
+		}
+		w.WriteHeader(200)
+		w.Write([]byte(`{"resourceType": "ValueSet", "id": "x"}`))
+	}))
+	defer server.Close()
+	apiClient := aidbox.NewApiClient(server.URL, "foo", "bar")
+	newSet := func(resources ...string) *schema.ResourceData {
+		dir := t.TempDir()
+		for i, resource := range resources {
+			assert.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.json", i)), []byte(resource), 0o600))
+		}
+		return schema.TestResourceDataRaw(t, resourceSchemaResourceSet(), map[string]interface{}{"path": dir})
+	}
+
+	t.Run("should only warn about the entries that failed if others succeeded", func(t *testing.T) {
+		d := newSet(`{"resourceType": "ValueSet", "id": "broken"}`, `{"resourceType": "ValueSet", "id": "colours"}`)
+
+		diags := resourceResourceSetCreate(context.TODO(), d, apiClient)
+
+		assert.False(t, diags.HasError())
+		assert.Len(t, diags, 1)
+		assert.Equal(t, "Failed to apply ValueSet/broken", diags[0].Summary)
+		assert.NotEqual(t, "", d.Id())
+		assert.Equal(t, []string{"ValueSet/colours"}, slices.Collect(maps.Keys(d.Get("entries").(map[string]interface{}))))
+	})
+
+	t.Run("should fail if nothing succeeded", func(t *testing.T) {
+		d := newSet(`{"resourceType": "ValueSet", "id": "broken"}`)
+
+		diags := resourceResourceSetCreate(context.TODO(), d, apiClient)
+
+		assert.True(t, diags.HasError())
+	})
+}
@@ -54,7 +54,7 @@ func mapSDCConfigFromData(data *schema.ResourceData) (*aidbox.SDCConfig, error)
 	res := &aidbox.SDCConfig{}
 	res.ResourceType = "SDCConfig"
 	res.Name = data.Get("name").(string)
-	res.ID = data.Id()
+	res.ResourceBase = mapResourceBaseFromData(data)
 	if v, ok := data.GetOk("description"); ok {
 		res.Description = v.(string)
 	}
@@ -79,7 +79,7 @@ func mapSDCConfigFromData(data *schema.ResourceData) (*aidbox.SDCConfig, error)
 }
 
 func mapSDCConfigToData(res *aidbox.SDCConfig, data *schema.ResourceData) error {
-	data.SetId(res.ID)
+	mapResourceBaseToData(&res.ResourceBase, data)
 	data.Set("name", res.Name)
 	data.Set("description", res.Description)
 	data.Set("default", res.Default)
@@ -101,15 +101,15 @@ func resourceSDCConfigCreate(ctx context.Context, d *schema.ResourceData, meta i
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapSDCConfigFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSDCConfig())
 	}
 	res, err := apiClient.CreateSDCConfig(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSDCConfig())
 	}
 	err = mapSDCConfigToData(res, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSDCConfig())
 	}
 	return nil
 }
@@ -121,11 +121,11 @@ func resourceSDCConfigRead(ctx context.Context, d *schema.ResourceData, meta int
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSDCConfig())
 	}
 	err = mapSDCConfigToData(res, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSDCConfig())
 	}
 	return nil
 }
@@ -134,16 +134,16 @@ func resourceSDCConfigUpdate(ctx context.Context, d *schema.ResourceData, meta i
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapSDCConfigFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSDCConfig())
 	}
 
 	ac, err := apiClient.UpdateSDCConfig(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSDCConfig())
 	}
 	err = mapSDCConfigToData(ac, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSDCConfig())
 	}
 	return nil
 }
@@ -152,7 +152,7 @@ func resourceSDCConfigDelete(ctx context.Context, d *schema.ResourceData, meta i
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteSDCConfig(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSDCConfig())
 	}
 	return nil
 }
@@ -70,7 +70,7 @@ func resourceSchemaSearch() map[string]*schema.Schema {
 }
 
 func mapSearchFromData(data *schema.ResourceData) (*aidbox.Search, error) {
-	res := &aidbox.Search{}
+	res := &aidbox.Search{ResourceBase: mapResourceBaseFromData(data)}
 	res.Name = data.Get("name").(string)
 	res.ParamParser = data.Get("param_parser").(string)
 	res.Module = data.Get("module").(string)
@@ -90,7 +90,7 @@ func mapSearchFromData(data *schema.ResourceData) (*aidbox.Search, error) {
 }
 
 func mapSearchToData(res *aidbox.Search, data *schema.ResourceData) {
-	data.SetId(res.ID)
+	mapResourceBaseToData(&res.ResourceBase, data)
 	data.Set("name", res.Name)
 	data.Set("param_parser", res.ParamParser)
 	data.Set("module", res.Module)
@@ -109,11 +109,11 @@ func resourceSearchCreate(ctx context.Context, d *schema.ResourceData, meta inte
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapSearchFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearch())
 	}
 	res, err := apiClient.CreateSearch(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearch())
 	}
 	mapSearchToData(res, d)
 	return nil
@@ -126,7 +126,7 @@ func resourceSearchRead(ctx context.Context, d *schema.ResourceData, meta interf
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearch())
 	}
 	mapSearchToData(res, d)
 	return nil
@@ -136,11 +136,11 @@ func resourceSearchUpdate(ctx context.Context, d *schema.ResourceData, meta inte
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapSearchFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearch())
 	}
 	ac, err := apiClient.UpdateSearch(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearch())
 	}
 	mapSearchToData(ac, d)
 	return nil
@@ -150,7 +150,7 @@ func resourceSearchDelete(ctx context.Context, d *schema.ResourceData, meta inte
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteSearch(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearch())
 	}
 	return nil
 }
@@ -2,10 +2,10 @@ package provider
 
 import (
 	"context"
-	"log"
 	"strconv"
 	"strings"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
@@ -82,14 +82,14 @@ func resourceSchemaSearchParameter() map[string]*schema.Schema {
 }
 
 func mapSearchParameterFromData(data *schema.ResourceData) (*aidbox.SearchParameter, error) {
-	res := &aidbox.SearchParameter{}
+	res := &aidbox.SearchParameter{ResourceBase: mapResourceBaseFromData(data)}
 	res.Name = data.Get("name").(string)
 	res.Module = data.Get("module").(string)
 
 	// type
 	t, err := aidbox.ParseSearchParameterType(data.Get("type").(string))
 	if err != nil {
-		log.Panicln(err)
+		return nil, err
 	}
 	res.Type = t
 
@@ -129,11 +129,11 @@ func mapSearchParameterFromData(data *schema.ResourceData) (*aidbox.SearchParame
 	return res, nil
 }
 
-func mapSearchParameterToData(res *aidbox.SearchParameter, data *schema.ResourceData) {
-	data.SetId(res.ID)
+func mapSearchParameterToData(res *aidbox.SearchParameter, data *schema.ResourceData) diag.Diagnostics {
+	mapResourceBaseToData(&res.ResourceBase, data)
 	data.Set("name", res.Name)
 	data.Set("module", res.Module)
-	data.Set("type", res.Type.ToString())
+	data.Set("type", string(res.Type))
 
 	// expression
 	var expression []interface{}
@@ -166,20 +166,20 @@ func mapSearchParameterToData(res *aidbox.SearchParameter, data *schema.Resource
 		"resource_type": res.Resource.ResourceType,
 	}
 	data.Set("reference", append(ref, r))
+	return unknownEnumWarning(cty.GetAttrPath("type"), res.Type)
 }
 
 func resourceSearchParameterCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapSearchParameterFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameter())
 	}
 	res, err := apiClient.CreateSearchParameter(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameter())
 	}
-	mapSearchParameterToData(res, d)
-	return nil
+	return mapSearchParameterToData(res, d)
 }
 
 func resourceSearchParameterRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -189,31 +189,29 @@ func resourceSearchParameterRead(ctx context.Context, d *schema.ResourceData, me
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameter())
 	}
-	mapSearchParameterToData(res, d)
-	return nil
+	return mapSearchParameterToData(res, d)
 }
 
 func resourceSearchParameterUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapSearchParameterFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameter())
 	}
 	ac, err := apiClient.UpdateSearchParameter(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameter())
 	}
-	mapSearchParameterToData(ac, d)
-	return nil
+	return mapSearchParameterToData(ac, d)
 }
 
 func resourceSearchParameterDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteSearchParameter(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameter())
 	}
 	return nil
 }
@@ -224,6 +222,8 @@ func resourceSearchParameterImport(ctx context.Context, d *schema.ResourceData,
 	if err != nil {
 		return nil, err
 	}
-	mapSearchParameterToData(res, d)
+	if err := logImportDiagnostics(ctx, mapSearchParameterToData(res, d)); err != nil {
+		return nil, err
+	}
 	return []*schema.ResourceData{d}, nil
 }
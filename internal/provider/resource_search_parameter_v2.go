@@ -2,11 +2,12 @@ package provider
 
 import (
 	"context"
-	"log"
 	"strings"
 
+	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
 	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
 )
 
@@ -24,6 +25,12 @@ func resourceSearchParameterV2() *schema.Resource {
 	}
 }
 
+// searchParameterComparators are the comparators of https://hl7.org/fhir/R4/valueset-search-comparator.html
+var searchParameterComparators = []string{"eq", "ne", "gt", "lt", "ge", "le", "sa", "eb", "ap"}
+
+// searchParameterModifiers are the modifiers of https://hl7.org/fhir/R4/valueset-search-modifier-code.html
+var searchParameterModifiers = []string{"missing", "exact", "contains", "not", "text", "in", "not-in", "below", "above", "type", "identifier", "ofType"}
+
 func resourceSchemaSearchParameterV2() map[string]*schema.Schema {
 	return map[string]*schema.Schema{
 		"name": {
@@ -32,9 +39,10 @@ func resourceSchemaSearchParameterV2() map[string]*schema.Schema {
 			Required:    true,
 		},
 		"type": {
-			Description: "Type of search parameter",
-			Type:        schema.TypeString,
-			Required:    true,
+			Description:  "Type of search parameter",
+			Type:         schema.TypeString,
+			Required:     true,
+			ValidateFunc: validation.StringInSlice(aidbox.SearchParameterTypesV2.Strings(), false),
 		},
 		"description": {
 			Description: "Natural language description of the search parameter",
@@ -71,11 +79,84 @@ func resourceSchemaSearchParameterV2() map[string]*schema.Schema {
 			Type:        schema.TypeString,
 			Required:    true,
 		},
+		"version": {
+			Description: "Business version of the search parameter",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"derived_from": {
+			Description: "Canonical URL of the search parameter this one is derived from",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"target": {
+			Description: "Types of the resources a reference search parameter can refer to",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"multiple_or": {
+			Description: "Whether a search can have more than one value separated by commas, the server's default if not set",
+			Type:        schema.TypeBool,
+			Optional:    true,
+		},
+		"multiple_and": {
+			Description: "Whether the parameter can be repeated in a search, the server's default if not set",
+			Type:        schema.TypeBool,
+			Optional:    true,
+		},
+		"comparator": {
+			Description: "Comparators supported, any of (eq|ne|gt|lt|ge|le|sa|eb|ap)",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringInSlice(searchParameterComparators, false),
+			},
+		},
+		"modifier": {
+			Description: "Modifiers supported, any of (missing|exact|contains|not|text|in|not-in|below|above|type|identifier|ofType)",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: validation.StringInSlice(searchParameterModifiers, false),
+			},
+		},
+		"chain": {
+			Description: "Names of the search parameters which can be chained to this one",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type: schema.TypeString,
+			},
+		},
+		"component": {
+			Description: "Parts of a composite search parameter",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"definition": {
+						Description: "Canonical URL of the search parameter the part is defined by",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+					"expression": {
+						Description: "FHIRPath expression extracting the part, relative to the value of the composite parameter's expression",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+				},
+			},
+		},
 	}
 }
 
 func mapSearchParameterV2FromData(data *schema.ResourceData) (*aidbox.SearchParameterV2, error) {
-	res := &aidbox.SearchParameterV2{}
+	res := &aidbox.SearchParameterV2{ResourceBase: mapResourceBaseFromData(data)}
 	res.Name = data.Get("name").(string)
 	res.Description = data.Get("description").(string)
 	res.Url = data.Get("url").(string)
@@ -83,6 +164,21 @@ func mapSearchParameterV2FromData(data *schema.ResourceData) (*aidbox.SearchPara
 	res.Status = data.Get("status").(string)
 	res.Expression = data.Get("expression").(string)
 	res.ResourceType = "SearchParameter"
+	res.Version = data.Get("version").(string)
+	res.DerivedFrom = data.Get("derived_from").(string)
+	res.Target = toStrings(data.Get("target"))
+	res.Comparator = toStrings(data.Get("comparator"))
+	res.Modifier = toStrings(data.Get("modifier"))
+	res.Chain = toStrings(data.Get("chain"))
+	res.MultipleOr = optionalBool(data, "multiple_or")
+	res.MultipleAnd = optionalBool(data, "multiple_and")
+	for _, c := range data.Get("component").([]interface{}) {
+		componentData := c.(map[string]interface{})
+		res.Component = append(res.Component, aidbox.SearchParameterComponentV2{
+			Definition: componentData["definition"].(string),
+			Expression: componentData["expression"].(string),
+		})
+	}
 
 	// base
 	rawBase := data.Get("base").([]interface{})
@@ -95,7 +191,7 @@ func mapSearchParameterV2FromData(data *schema.ResourceData) (*aidbox.SearchPara
 	// type
 	t, err := aidbox.ParseSearchParameterTypeV2(data.Get("type").(string))
 	if err != nil {
-		log.Panicln(err)
+		return nil, err
 	}
 	res.Type = t
 
@@ -104,30 +200,46 @@ func mapSearchParameterV2FromData(data *schema.ResourceData) (*aidbox.SearchPara
 	return res, nil
 }
 
-func mapSearchParameterV2ToData(res *aidbox.SearchParameterV2, data *schema.ResourceData) {
-	data.SetId(res.ID)
+func mapSearchParameterV2ToData(res *aidbox.SearchParameterV2, data *schema.ResourceData) diag.Diagnostics {
+	mapResourceBaseToData(&res.ResourceBase, data)
 	data.Set("base", res.Base)
 	data.Set("code", res.Code)
 	data.Set("description", res.Description)
 	data.Set("expression", res.Expression)
 	data.Set("name", res.Name)
 	data.Set("status", res.Status)
-	data.Set("type", res.Type.ToString())
+	data.Set("type", string(res.Type))
 	data.Set("url", res.Url)
+	data.Set("version", res.Version)
+	data.Set("derived_from", res.DerivedFrom)
+	data.Set("target", res.Target)
+	data.Set("comparator", res.Comparator)
+	data.Set("modifier", res.Modifier)
+	data.Set("chain", res.Chain)
+	setOptionalBool(data, "multiple_or", res.MultipleOr)
+	setOptionalBool(data, "multiple_and", res.MultipleAnd)
+	var components []interface{}
+	for _, component := range res.Component {
+		components = append(components, map[string]interface{}{
+			"definition": component.Definition,
+			"expression": component.Expression,
+		})
+	}
+	data.Set("component", components)
+	return unknownEnumWarning(cty.GetAttrPath("type"), res.Type)
 }
 
 func resourceSearchParameterV2Create(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapSearchParameterV2FromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameterV2())
 	}
 	res, err := apiClient.CreateSearchParameterV2(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameterV2())
 	}
-	mapSearchParameterV2ToData(res, d)
-	return nil
+	return mapSearchParameterV2ToData(res, d)
 }
 
 func resourceSearchParameterV2Read(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -137,31 +249,29 @@ func resourceSearchParameterV2Read(ctx context.Context, d *schema.ResourceData,
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameterV2())
 	}
-	mapSearchParameterV2ToData(res, d)
-	return nil
+	return mapSearchParameterV2ToData(res, d)
 }
 
 func resourceSearchParameterV2Update(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapSearchParameterV2FromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameterV2())
 	}
 	ac, err := apiClient.UpdateSearchParameterV2(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameterV2())
 	}
-	mapSearchParameterV2ToData(ac, d)
-	return nil
+	return mapSearchParameterV2ToData(ac, d)
 }
 
 func resourceSearchParameterV2Delete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteSearchParameterV2(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaSearchParameterV2())
 	}
 	return nil
 }
@@ -172,6 +282,8 @@ func resourceSearchParameterV2Import(ctx context.Context, d *schema.ResourceData
 	if err != nil {
 		return nil, err
 	}
-	mapSearchParameterV2ToData(res, d)
+	if err := logImportDiagnostics(ctx, mapSearchParameterV2ToData(res, d)); err != nil {
+		return nil, err
+	}
 	return []*schema.ResourceData{d}, nil
 }
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"github.com/stretchr/testify/assert"
 )
 
 func TestAccResourceSearchParameterV2_elementNameAndPatternFilterInExpression(t *testing.T) {
@@ -113,6 +115,79 @@ func TestAccResourceSearchParameterV2_update(t *testing.T) {
 	})
 }
 
+func TestAccResourceSearchParameterV2_composite(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { requireSchemaMode(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceSearchParameterV2_composite,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_fhir_search_parameter.example_composite", "type", "composite"),
+					resource.TestCheckResourceAttr("aidbox_fhir_search_parameter.example_composite", "component.#", "2"),
+					resource.TestCheckResourceAttr("aidbox_fhir_search_parameter.example_composite", "component.0.definition", "http://hl7.org/fhir/SearchParameter/clinical-code"),
+					resource.TestCheckResourceAttr("aidbox_fhir_search_parameter.example_composite", "component.1.expression", "value.as(Quantity)"),
+					resource.TestCheckResourceAttr("aidbox_fhir_search_parameter.example_composite", "multiple_or", "false"),
+					resource.TestCheckResourceAttr("aidbox_fhir_search_parameter.example_composite", "comparator.#", "2"),
+					resource.TestCheckResourceAttr("aidbox_fhir_search_parameter.example_composite", "version", "1.0.0"),
+				),
+			},
+			{
+				ResourceName:      "aidbox_fhir_search_parameter.example_composite",
+				ImportState:       true,
+				ImportStateVerify: true,
+			},
+		},
+	})
+}
+
+func TestAccResourceSearchParameterV2_reference(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { requireSchemaMode(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceSearchParameterV2_reference,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_fhir_search_parameter.example_reference", "target.0", "Practitioner"),
+					resource.TestCheckResourceAttr("aidbox_fhir_search_parameter.example_reference", "modifier.0", "missing"),
+					resource.TestCheckResourceAttr("aidbox_fhir_search_parameter.example_reference", "chain.0", "name"),
+				),
+			},
+		},
+	})
+}
+
+func TestMapSearchParameterV2(t *testing.T) {
+	res := &aidbox.SearchParameterV2{
+		ResourceBase: aidbox.ResourceBase{ID: "Observation.code-value-quantity"},
+		ResourceType: "SearchParameter",
+		Name:         "code-value-quantity",
+		Type:         aidbox.SearchParameterTypeV2Composite,
+		Expression:   "Observation",
+		Description:  "Code and quantity value",
+		Url:          "https://fhir.yourcompany.com/searchparameter/code-value-quantity",
+		Status:       "active",
+		Code:         "code-value-quantity",
+		Base:         []string{"Observation"},
+		Version:      "1.0.0",
+		DerivedFrom:  "http://hl7.org/fhir/SearchParameter/Observation-code-value-quantity",
+		Comparator:   []string{"eq", "gt"},
+		Component: []aidbox.SearchParameterComponentV2{
+			{Definition: "http://hl7.org/fhir/SearchParameter/clinical-code", Expression: "code"},
+			{Definition: "http://hl7.org/fhir/SearchParameter/Observation-value-quantity", Expression: "value.as(Quantity)"},
+		},
+	}
+	d := resourceSearchParameterV2().Data(nil)
+
+	diags := mapSearchParameterV2ToData(res, d)
+	mapped, err := mapSearchParameterV2FromData(d)
+
+	assert.Empty(t, diags)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, res, mapped)
+}
+
 const testAccResourceSearchParameterV2_elementNameAndPatternFilterInExpression = `
 resource "aidbox_fhir_search_parameter" "example_phone" {
   name        = "phone-number"
@@ -162,3 +237,41 @@ resource "aidbox_fhir_search_parameter" "example_phone" {
   url         = "https://fhir.newdomain.com/searchparameter/phone-number"
 }
 `
+
+const testAccResourceSearchParameterV2_composite = `
+resource "aidbox_fhir_search_parameter" "example_composite" {
+  name        = "code-value-quantity"
+  type        = "composite"
+  base        = ["Observation"]
+  code        = "code-value-quantity"
+  expression  = "Observation"
+  description = "Search observations by code and quantity value"
+  url         = "https://fhir.yourcompany.com/searchparameter/code-value-quantity"
+  version     = "1.0.0"
+  multiple_or = false
+  comparator  = ["eq", "gt"]
+  component {
+    definition = "http://hl7.org/fhir/SearchParameter/clinical-code"
+    expression = "code"
+  }
+  component {
+    definition = "http://hl7.org/fhir/SearchParameter/Observation-value-quantity"
+    expression = "value.as(Quantity)"
+  }
+}
+`
+
+const testAccResourceSearchParameterV2_reference = `
+resource "aidbox_fhir_search_parameter" "example_reference" {
+  name        = "gp"
+  type        = "reference"
+  base        = ["Patient"]
+  code        = "gp"
+  expression  = "Patient.generalPractitioner"
+  description = "Search patients by general practitioner"
+  url         = "https://fhir.yourcompany.com/searchparameter/gp"
+  target      = ["Practitioner"]
+  modifier    = ["missing"]
+  chain       = ["name"]
+}
+`
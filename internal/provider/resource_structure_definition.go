@@ -84,7 +84,7 @@ func resourceSchemaStructureDefinition() map[string]*schema.Schema {
 
 func mapStructureDefinitionFromData(data *schema.ResourceData) (*aidbox.StructureDefinition, error) {
 	res := &aidbox.StructureDefinition{}
-	res.ID = data.Id()
+	res.ResourceBase = mapResourceBaseFromData(data)
 	res.ResourceType = "StructureDefinition"
 	res.Name = data.Get("name").(string)
 	res.Url = data.Get("url").(string)
@@ -109,7 +109,7 @@ func mapStructureDefinitionFromData(data *schema.ResourceData) (*aidbox.Structur
 }
 
 func mapStructureDefinitionToData(res *aidbox.StructureDefinition, data *schema.ResourceData) error {
-	data.SetId(res.ID)
+	mapResourceBaseToData(&res.ResourceBase, data)
 
 	data.Set("name", res.Name)
 	data.Set("url", res.Url)
@@ -134,15 +134,15 @@ func resourceStructureDefinitionCreate(ctx context.Context, d *schema.ResourceDa
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapStructureDefinitionFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinition())
 	}
 	res, err := apiClient.CreateStructureDefinition(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinition())
 	}
 	err = mapStructureDefinitionToData(res, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinition())
 	}
 	return nil
 }
@@ -154,11 +154,11 @@ func resourceStructureDefinitionRead(ctx context.Context, d *schema.ResourceData
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinition())
 	}
 	err = mapStructureDefinitionToData(res, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinition())
 	}
 	return nil
 }
@@ -167,15 +167,15 @@ func resourceStructureDefinitionUpdate(ctx context.Context, d *schema.ResourceDa
 	apiClient := meta.(*aidbox.ApiClient)
 	q, err := mapStructureDefinitionFromData(d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinition())
 	}
 	ac, err := apiClient.UpdateStructureDefinition(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinition())
 	}
 	err = mapStructureDefinitionToData(ac, d)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinition())
 	}
 	return nil
 }
@@ -184,7 +184,7 @@ func resourceStructureDefinitionDelete(ctx context.Context, d *schema.ResourceDa
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteStructureDefinition(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinition())
 	}
 	return nil
 }
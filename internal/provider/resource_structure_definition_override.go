@@ -22,7 +22,8 @@ func resourceStructureDefinitionOverride() *schema.Resource {
 		ReadContext:   resourceStructureDefinitionOverrideRead,
 		UpdateContext: resourceStructureDefinitionOverrideUpdate,
 		DeleteContext: resourceStructureDefinitionOverrideDelete,
-		Schema:        resourceFullSchema(resourceSchemaStructureDefinitionOverride()),
+		// no version_id, the override is written by url whatever version the StructureDefinition is at
+		Schema: resourceSchemaStructureDefinitionOverride(),
 	}
 }
 
@@ -64,7 +65,7 @@ func resourceStructureDefinitionOverrideCreate(ctx context.Context, d *schema.Re
 	originalSD, err := apiClient.GetStructureDefinitionByUrl(ctx, canonicalUrl)
 
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 
 	originalSDBytes, err := json.Marshal(originalSD)
@@ -75,13 +76,13 @@ func resourceStructureDefinitionOverrideCreate(ctx context.Context, d *schema.Re
 	overrideSD := map[string]interface{}{}
 	err = json.Unmarshal([]byte(overrideSDString), &overrideSD)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 
 	// now update the SD to our customized version
 	updatedSD, err := apiClient.UpdateStructureDefinitionByUrl(ctx, &overrideSD, canonicalUrl)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 	var updatedUrl = (*updatedSD)["url"].(string)
 	if updatedUrl != canonicalUrl {
@@ -96,7 +97,7 @@ func resourceStructureDefinitionOverrideCreate(ctx context.Context, d *schema.Re
 	updatedSDBytes, err := json.Marshal(updatedSD)
 	updatedSDString := string(updatedSDBytes)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 	d.Set("structure_definition_override", updatedSDString)
 
@@ -112,7 +113,7 @@ func resourceStructureDefinitionOverrideRead(ctx context.Context, d *schema.Reso
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 
 	var overrideSDUrl = (*overrideSD)["url"].(string)
@@ -128,7 +129,7 @@ func resourceStructureDefinitionOverrideRead(ctx context.Context, d *schema.Reso
 	overrideSDBytes, err := json.Marshal(overrideSD)
 	overrideSDString := string(overrideSDBytes)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 	d.Set("structure_definition_override", overrideSDString)
 
@@ -144,20 +145,20 @@ func resourceStructureDefinitionOverrideUpdate(ctx context.Context, d *schema.Re
 	overrideSD := map[string]interface{}{}
 	err := json.Unmarshal([]byte(overrideSDString), &overrideSD)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 
 	// update the SD to our new customized version
 	updatedSD, err := apiClient.UpdateStructureDefinitionByUrl(ctx, &overrideSD, canonicalUrl)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 	// throw away the id we didn't know upfront, it just adds unnecessary complexity here when comparing states
 	delete(*updatedSD, "id")
 
 	updatedSDBytes, err := json.Marshal(updatedSD)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 	d.Set("structure_definition_override", string(updatedSDBytes))
 
@@ -174,12 +175,12 @@ func resourceStructureDefinitionOverrideDelete(ctx context.Context, d *schema.Re
 	originalSD := map[string]interface{}{}
 	err := json.Unmarshal([]byte(originalSDString), &originalSD)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 
 	// restore the SD to the spec version
 	if _, err := apiClient.UpdateStructureDefinitionByUrl(ctx, &originalSD, canonicalUrl); err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaStructureDefinitionOverride())
 	}
 
 	return nil
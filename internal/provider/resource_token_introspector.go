@@ -2,7 +2,6 @@ package provider
 
 import (
 	"context"
-	"log"
 
 	"github.com/hashicorp/go-cty/cty"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
@@ -86,9 +85,9 @@ func resourceSchemaTokenIntrospector() map[string]*schema.Schema {
 		},
 	}
 }
-func mapTokenIntrospectorToData(v *aidbox.TokenIntrospector, data *schema.ResourceData) {
+func mapTokenIntrospectorToData(v *aidbox.TokenIntrospector, data *schema.ResourceData) diag.Diagnostics {
 	mapResourceBaseToData(&v.ResourceBase, data)
-	data.Set("type", v.Type.ToString())
+	data.Set("type", string(v.Type))
 
 	if v.TokenIntrospectionEndpoint != nil {
 		ti := map[string]interface{}{
@@ -107,6 +106,7 @@ func mapTokenIntrospectorToData(v *aidbox.TokenIntrospector, data *schema.Resour
 		}
 		data.Set("jwt", []interface{}{jwt})
 	}
+	return unknownEnumWarning(cty.GetAttrPath("type"), v.Type)
 }
 
 func mapTokenIntrospectorFromData(d *schema.ResourceData) *aidbox.TokenIntrospector {
@@ -114,11 +114,7 @@ func mapTokenIntrospectorFromData(d *schema.ResourceData) *aidbox.TokenIntrospec
 		ResourceBase: mapResourceBaseFromData(d),
 	}
 
-	if tt, err := aidbox.ParseTokenIntrospectorType(d.Get("type").(string)); err == nil {
-		vv.Type = tt
-	} else {
-		log.Panicf("Error parsing token introspector type %v", err)
-	}
+	vv.Type = aidbox.TokenIntrospectorType(d.Get("type").(string))
 
 	if v, ok := d.GetOk("introspection_endpoint"); ok {
 		introspectionEndpointData := v.([]interface{})[0].(map[string]interface{}) // Ugly
@@ -146,10 +142,9 @@ func resourceTokenIntrospectorCreate(ctx context.Context, d *schema.ResourceData
 	q := mapTokenIntrospectorFromData(d)
 	res, err := apiClient.CreateTokenIntrospector(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaTokenIntrospector())
 	}
-	mapTokenIntrospectorToData(res, d)
-	return nil
+	return mapTokenIntrospectorToData(res, d)
 }
 
 func resourceTokenIntrospectorRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -160,10 +155,9 @@ func resourceTokenIntrospectorRead(ctx context.Context, d *schema.ResourceData,
 		if handleNotFoundError(err, d) {
 			return nil
 		}
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaTokenIntrospector())
 	}
-	mapTokenIntrospectorToData(res, d)
-	return nil
+	return mapTokenIntrospectorToData(res, d)
 }
 
 func resourceTokenIntrospectorUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
@@ -171,17 +165,16 @@ func resourceTokenIntrospectorUpdate(ctx context.Context, d *schema.ResourceData
 	q := mapTokenIntrospectorFromData(d)
 	ti, err := apiClient.UpdateTokenIntrospector(ctx, q)
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaTokenIntrospector())
 	}
-	mapTokenIntrospectorToData(ti, d)
-	return nil
+	return mapTokenIntrospectorToData(ti, d)
 }
 
 func resourceTokenIntrospectorDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
 	apiClient := meta.(*aidbox.ApiClient)
 	err := apiClient.DeleteTokenIntrospector(ctx, d.Id())
 	if err != nil {
-		return diag.FromErr(err)
+		return diagFromErr(err, resourceSchemaTokenIntrospector())
 	}
 	return nil
 }
@@ -192,6 +185,8 @@ func resourceTokenIntrospectorImport(ctx context.Context, d *schema.ResourceData
 	if err != nil {
 		return nil, err
 	}
-	mapTokenIntrospectorToData(res, d)
+	if err := logImportDiagnostics(ctx, mapTokenIntrospectorToData(res, d)); err != nil {
+		return nil, err
+	}
 	return []*schema.ResourceData{d}, nil
 }
@@ -0,0 +1,281 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/validation"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+)
+
+func resourceUser() *schema.Resource {
+	return &schema.Resource{
+		Description: "User https://docs.aidbox.app/modules/security-and-access-control/readme-1/overview#user. " +
+			"The password is write-only, which needs terraform 1.11 or later: it's neither stored in the state nor read " +
+			"back from the server, so increment `password_wo_version` to change it.",
+		CreateContext: resourceUserCreate,
+		ReadContext:   resourceUserRead,
+		UpdateContext: resourceUserUpdate,
+		DeleteContext: resourceUserDelete,
+		Importer: &schema.ResourceImporter{
+			StateContext: resourceUserImport,
+		},
+		Schema: resourceFullSchema(resourceSchemaUser()),
+	}
+}
+
+func resourceSchemaUser() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"user_id": {
+			Description: "ID of the user, which can be used to log in. Generated by the server if not set.",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Computed:    true,
+			ForceNew:    true,
+		},
+		"password": {
+			Description: "Password of the user, only ever sent to the server",
+			Type:        schema.TypeString,
+			Optional:    true,
+			Sensitive:   true,
+			WriteOnly:   true,
+		},
+		"password_wo_version": {
+			Description: "Version of the password, change it to update the user with the password configured now",
+			Type:        schema.TypeInt,
+			Optional:    true,
+		},
+		"email": {
+			Description: "Email address of the user, which can be used to log in",
+			Type:        schema.TypeString,
+			Optional:    true,
+		},
+		"active": {
+			Description: "Whether the user is active",
+			Type:        schema.TypeBool,
+			Optional:    true,
+		},
+		"name": {
+			Description: "Name of the user",
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"given_name": {
+						Description: "Given name of the user",
+						Type:        schema.TypeString,
+						Optional:    true,
+					},
+					"family_name": {
+						Description: "Family name of the user",
+						Type:        schema.TypeString,
+						Optional:    true,
+					},
+				},
+			},
+		},
+		"data": {
+			Description:      "Arbitrary data about the user in JSON format",
+			Type:             schema.TypeString,
+			Optional:         true,
+			ValidateFunc:     validation.StringIsJSON,
+			DiffSuppressFunc: jsonDiffSuppressFunc,
+		},
+		"fhir_user": {
+			Description: "The FHIR resource representing the user, e.g. a Practitioner",
+			Type:        schema.TypeList,
+			Optional:    true,
+			MaxItems:    1,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"resource_type": {
+						Description: "Type of the resource, e.g. Practitioner",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+					"resource_id": {
+						Description: "ID of the resource",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+				},
+			},
+		},
+		"identifier": {
+			Description: "Business identifiers of the user",
+			Type:        schema.TypeList,
+			Optional:    true,
+			Elem: &schema.Resource{
+				Schema: map[string]*schema.Schema{
+					"system": {
+						Description: "Namespace of the identifier",
+						Type:        schema.TypeString,
+						Optional:    true,
+					},
+					"value": {
+						Description: "Value of the identifier",
+						Type:        schema.TypeString,
+						Required:    true,
+					},
+				},
+			},
+		},
+		"two_factor_enabled": {
+			Description: "if 2FA is enabled for the user",
+			Type:        schema.TypeBool,
+			Computed:    true,
+		},
+	}
+}
+
+// mapUserToData sets everything but the password, which isn't returned by the server
+func mapUserToData(res *aidbox.User, data *schema.ResourceData) {
+	mapResourceBaseToData(&res.ResourceBase, data)
+	data.Set("email", res.Email)
+	data.Set("active", res.Active)
+
+	var name []interface{}
+	if res.Name != nil {
+		name = []interface{}{map[string]interface{}{
+			"given_name":  res.Name.GivenName,
+			"family_name": res.Name.FamilyName,
+		}}
+	}
+	data.Set("name", name)
+
+	if len(res.Data) > 0 {
+		data.Set("data", string(res.Data))
+	} else {
+		data.Set("data", "")
+	}
+
+	var fhirUser []interface{}
+	if res.FhirUser != nil {
+		fhirUser = []interface{}{map[string]interface{}{
+			"resource_type": res.FhirUser.ResourceType,
+			"resource_id":   res.FhirUser.ResourceId,
+		}}
+	}
+	data.Set("fhir_user", fhirUser)
+
+	var identifiers []interface{}
+	for _, identifier := range res.Identifier {
+		identifiers = append(identifiers, map[string]interface{}{
+			"system": identifier.System,
+			"value":  identifier.Value,
+		})
+	}
+	data.Set("identifier", identifiers)
+
+	data.Set("two_factor_enabled", res.TwoFactor != nil && res.TwoFactor.Enabled)
+}
+
+func mapUserFromData(d *schema.ResourceData) *aidbox.User {
+	res := &aidbox.User{}
+	res.ResourceBase = mapResourceBaseFromData(d)
+	if res.ID == "" {
+		res.ID = d.Get("user_id").(string)
+	}
+	res.Password = configuredPassword(d)
+	res.Email = d.Get("email").(string)
+	res.Active = d.Get("active").(bool)
+	if v, ok := d.GetOk("name"); ok && v.([]interface{})[0] != nil {
+		nameData := v.([]interface{})[0].(map[string]interface{})
+		res.Name = &aidbox.UserName{
+			GivenName:  nameData["given_name"].(string),
+			FamilyName: nameData["family_name"].(string),
+		}
+	}
+	if v, ok := d.GetOk("data"); ok {
+		res.Data = json.RawMessage(v.(string))
+	}
+	if v, ok := d.GetOk("fhir_user"); ok {
+		fhirUserData := v.([]interface{})[0].(map[string]interface{})
+		res.FhirUser = &aidbox.Reference{
+			ResourceType: fhirUserData["resource_type"].(string),
+			ResourceId:   fhirUserData["resource_id"].(string),
+		}
+	}
+	for _, v := range d.Get("identifier").([]interface{}) {
+		identifierData := v.(map[string]interface{})
+		res.Identifier = append(res.Identifier, aidbox.Identifier{
+			System: identifierData["system"].(string),
+			Value:  identifierData["value"].(string),
+		})
+	}
+	return res
+}
+
+// configuredPassword is the password from the configuration, the only place a write-only attribute can be read from.
+// It's available while applying, but not when importing.
+func configuredPassword(d *schema.ResourceData) string {
+	config := d.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return ""
+	}
+	value := config.GetAttr("password")
+	if value.IsNull() || !value.IsKnown() {
+		return ""
+	}
+	return value.AsString()
+}
+
+func resourceUserCreate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	q := mapUserFromData(d)
+	res, err := apiClient.CreateUser(ctx, q)
+	if err != nil {
+		return diagFromErr(err, resourceSchemaUser())
+	}
+	mapUserToData(res, d)
+	d.Set("user_id", res.ID)
+	return nil
+}
+
+func resourceUserRead(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	res, err := apiClient.GetUser(ctx, d.Id())
+	if err != nil {
+		if handleNotFoundError(err, d) {
+			return nil
+		}
+		return diagFromErr(err, resourceSchemaUser())
+	}
+	mapUserToData(res, d)
+	d.Set("user_id", res.ID)
+	return nil
+}
+
+func resourceUserUpdate(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	q := mapUserFromData(d)
+	res, err := apiClient.UpdateUser(ctx, q)
+	if err != nil {
+		return diagFromErr(err, resourceSchemaUser())
+	}
+	mapUserToData(res, d)
+	return nil
+}
+
+func resourceUserDelete(ctx context.Context, d *schema.ResourceData, meta interface{}) diag.Diagnostics {
+	apiClient := meta.(*aidbox.ApiClient)
+	err := apiClient.DeleteUser(ctx, d.Id())
+	if err != nil {
+		return diagFromErr(err, resourceSchemaUser())
+	}
+	return nil
+}
+
+func resourceUserImport(ctx context.Context, d *schema.ResourceData, meta interface{}) ([]*schema.ResourceData, error) {
+	apiClient := meta.(*aidbox.ApiClient)
+	res, err := apiClient.GetUser(ctx, d.Id())
+	if err != nil {
+		return nil, err
+	}
+	mapUserToData(res, d)
+	d.Set("user_id", res.ID)
+	return []*schema.ResourceData{d}, nil
+}
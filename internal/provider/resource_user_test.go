@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/resource"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAccResourceUser(t *testing.T) {
+	resource.Test(t, resource.TestCase{
+		PreCheck:          func() { testAccPreCheck(t) },
+		ProviderFactories: testProviderFactories,
+		Steps: []resource.TestStep{
+			{
+				Config: testAccResourceUser,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_user.ridcully", "id", "ridcully"),
+					resource.TestCheckResourceAttr("aidbox_user.ridcully", "email", "archchancellor@unseen.edu"),
+					resource.TestCheckResourceAttr("aidbox_user.ridcully", "active", "true"),
+					resource.TestCheckResourceAttr("aidbox_user.ridcully", "name.0.family_name", "Ridcully"),
+					resource.TestCheckResourceAttr("aidbox_user.ridcully", "fhir_user.0.resource_type", "Practitioner"),
+					resource.TestCheckResourceAttr("aidbox_user.ridcully", "identifier.0.value", "1"),
+					resource.TestCheckNoResourceAttr("aidbox_user.ridcully", "password"),
+				),
+			},
+			{
+				ResourceName:            "aidbox_user.ridcully",
+				ImportState:             true,
+				ImportStateVerify:       true,
+				ImportStateVerifyIgnore: []string{"password_wo_version"},
+			},
+			{
+				Config: testAccResourceUser_lookup,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("data.aidbox_user.by_email", "id", "ridcully"),
+					resource.TestCheckResourceAttr("data.aidbox_user.by_identifier", "id", "ridcully"),
+					resource.TestCheckResourceAttr("data.aidbox_user.by_identifier", "name.0.given_name", "Mustrum"),
+				),
+			},
+			{
+				Config: testAccResourceUser_newPassword,
+				Check: resource.ComposeTestCheckFunc(
+					resource.TestCheckResourceAttr("aidbox_user.ridcully", "password_wo_version", "2"),
+					resource.TestCheckNoResourceAttr("aidbox_user.ridcully", "password"),
+				),
+			},
+		},
+	})
+}
+
+func TestConfiguredPassword(t *testing.T) {
+	d := resourceUser().Data(&terraform.InstanceState{
+		RawConfig: cty.ObjectVal(map[string]cty.Value{"password": cty.StringVal("librarian")}),
+	})
+
+	assert.Equal(t, "librarian", mapUserFromData(d).Password)
+	// e.g. when importing
+	assert.Equal(t, "", configuredPassword(resourceUser().Data(nil)))
+}
+
+const testAccResourceUser = `
+resource "aidbox_user" "ridcully" {
+  user_id             = "ridcully"
+  password            = "librarian"
+  password_wo_version = 1
+  email               = "archchancellor@unseen.edu"
+  active              = true
+  name {
+    given_name  = "Mustrum"
+    family_name = "Ridcully"
+  }
+  data = jsonencode({ faculty = "Archchancellor" })
+  fhir_user {
+    resource_type = "Practitioner"
+    resource_id   = "ridcully"
+  }
+  identifier {
+    system = "https://unseen.edu/staff"
+    value  = "1"
+  }
+}
+`
+
+const testAccResourceUser_newPassword = `
+resource "aidbox_user" "ridcully" {
+  user_id             = "ridcully"
+  password            = "ook"
+  password_wo_version = 2
+  email               = "archchancellor@unseen.edu"
+  active              = true
+}
+`
+
+const testAccResourceUser_lookup = testAccResourceUser + `
+data "aidbox_user" "by_email" {
+  email = aidbox_user.ridcully.email
+}
+
+data "aidbox_user" "by_identifier" {
+  identifier_system = "https://unseen.edu/staff"
+  identifier_value  = aidbox_user.ridcully.identifier[0].value
+}
+`
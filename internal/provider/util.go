@@ -1,16 +1,25 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"reflect"
+	"regexp"
+	"strings"
 
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-log/tflog"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
 	"github.com/hashicorp/terraform-plugin-sdk/v2/helper/schema"
 	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"gopkg.in/yaml.v3"
 )
 
 func handleNotFoundError(err error, data *schema.ResourceData) bool {
-	if err == aidbox.NotFoundError {
+	if errors.Is(err, aidbox.NotFoundError) {
 		log.Printf("[WARN] Removing resource with id %s from state as it no longer exists", data.Id())
 		data.SetId("")
 		return true
@@ -18,6 +27,49 @@ func handleNotFoundError(err error, data *schema.ResourceData) bool {
 	return false
 }
 
+// toStrings converts the value of a list or set of strings, with unknown or null elements as empty strings
+func toStrings(v interface{}) []string {
+	var strs []string
+	for _, s := range v.([]interface{}) {
+		str, _ := s.(string)
+		strs = append(strs, str)
+	}
+	return strs
+}
+
+// optionalBool is the value of a bool attribute as configured, nil if it isn't set so that the server's default applies.
+// An explicit false can't be told apart from an unset attribute in the state, hence the configuration.
+func optionalBool(data *schema.ResourceData, key string) *bool {
+	config := data.GetRawConfig()
+	if config.IsNull() || !config.IsKnown() {
+		return nil
+	}
+	value := config.GetAttr(key)
+	if value.IsNull() || !value.IsKnown() {
+		return nil
+	}
+	b := value.True()
+	return &b
+}
+
+// setOptionalBool leaves the attribute unset if the server doesn't have a value for it
+func setOptionalBool(data *schema.ResourceData, key string, value *bool) {
+	if value == nil {
+		data.Set(key, nil)
+	} else {
+		data.Set(key, *value)
+	}
+}
+
+// toStringMap converts the value of a map of strings
+func toStringMap(v interface{}) map[string]string {
+	strs := map[string]string{}
+	for key, value := range v.(map[string]interface{}) {
+		strs[key] = value.(string)
+	}
+	return strs
+}
+
 func jsonDiffSuppressFunc(_ string, oldJson string, newJson string, _ *schema.ResourceData) bool {
 	if oldJson == "" && newJson != "" {
 		return false
@@ -38,3 +90,220 @@ func jsonDiffSuppressFunc(_ string, oldJson string, newJson string, _ *schema.Re
 	}
 	return reflect.DeepEqual(oldObject, newObject)
 }
+
+// yamlToJson converts a YAML document to JSON, which it has to be a mapping for
+func yamlToJson(yamlString string) (string, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal([]byte(yamlString), &document)
+	if err != nil {
+		return "", err
+	}
+	if len(document.Content) == 0 || document.Content[0].Kind != yaml.MappingNode {
+		return "", errors.New("expected a YAML mapping")
+	}
+	value, err := yamlNodeToValue(document.Content[0])
+	if err != nil {
+		return "", err
+	}
+	jsonBytes, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(jsonBytes), nil
+}
+
+// yamlNodeToValue decodes the node the way encoding/json would decode the same content in JSON. Unlike yaml.Unmarshal
+// it keeps dates such as 2024-01-01 as they are, rather than turning them into timestamps.
+func yamlNodeToValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return yamlNodeToValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlNodeToValue(node.Alias)
+	case yaml.MappingNode:
+		h := map[string]interface{}{}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			value, err := yamlNodeToValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			h[node.Content[i].Value] = value
+		}
+		return h, nil
+	case yaml.SequenceNode:
+		values := []interface{}{}
+		for _, element := range node.Content {
+			value, err := yamlNodeToValue(element)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	}
+	switch node.ShortTag() {
+	case "!!int", "!!float", "!!bool", "!!null":
+		var value interface{}
+		err := node.Decode(&value)
+		return value, err
+	}
+	return node.Value, nil
+}
+
+// jsonToYaml converts a JSON document to YAML, with the fields in alphabetical order
+func jsonToYaml(jsonString string) (string, error) {
+	var h interface{}
+	err := json.Unmarshal([]byte(jsonString), &h)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(h)
+	if err != nil {
+		return "", err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// diagFromErr reports every issue of the OperationOutcome aidbox responded with as its own diagnostic, pointing at the
+// attribute of the resource's schema it's about where that can be told from the issue's expression. Resources with a
+// JSON document rather than attributes have no schema to pass, and get the expression in the detail only. Any other
+// error is reported as it is.
+func diagFromErr(err error, attributes map[string]*schema.Schema) diag.Diagnostics {
+	var apiError *aidbox.APIError
+	if !errors.As(err, &apiError) || len(apiError.Issues) == 0 {
+		return diag.FromErr(err)
+	}
+	var diags diag.Diagnostics
+	failed := false
+	for _, issue := range apiError.Issues {
+		severity := diag.Warning
+		if issue.Severity == "fatal" || issue.Severity == "error" {
+			severity = diag.Error
+			failed = true
+		}
+		summary := issue.Diagnostics
+		if summary == "" {
+			summary = issue.Code
+		}
+		detail := fmt.Sprintf("%s %s responded with %s", apiError.Method, apiError.URL, apiError.Status)
+		if len(issue.Expression) > 0 {
+			detail += "\n\nat " + strings.Join(issue.Expression, ", ")
+		}
+		diags = append(diags, diag.Diagnostic{
+			Severity:      severity,
+			Summary:       summary,
+			Detail:        detail,
+			AttributePath: issueAttributePath(issue, attributes),
+		})
+	}
+	if !failed {
+		// the request failed nevertheless, make sure terraform doesn't treat it as success
+		diags = append(diags, diag.FromErr(err)...)
+	}
+	return diags
+}
+
+var expressionIndex = regexp.MustCompile(`\[\d+]`)
+var camelCaseHump = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+
+// issueAttributePath maps the first expression of the issue, e.g. "AccessPolicy.roleName" or ".engine", onto the top
+// level attribute it's about, e.g. "role_name". Nested elements are reported against their top level attribute, as
+// blocks don't map one to one onto the resource structure. It's nil if there's no such attribute in the schema.
+func issueAttributePath(issue aidbox.OperationOutcomeIssue, attributes map[string]*schema.Schema) cty.Path {
+	if len(issue.Expression) == 0 || len(attributes) == 0 {
+		return nil
+	}
+	segments := strings.Split(strings.TrimPrefix(expressionIndex.ReplaceAllString(issue.Expression[0], ""), "."), ".")
+	// FHIRPath expressions start with the resource type
+	if len(segments) > 1 && segments[0] != "" && segments[0][0] >= 'A' && segments[0][0] <= 'Z' {
+		segments = segments[1:]
+	}
+	if segments[0] == "" {
+		return nil
+	}
+	attribute := strings.ReplaceAll(strings.ToLower(camelCaseHump.ReplaceAllString(segments[0], "${1}_${2}")), "-", "_")
+	if _, ok := attributes[attribute]; !ok {
+		return nil
+	}
+	return cty.GetAttrPath(attribute)
+}
+
+// knownEnum is an enum of the aidbox package, which keeps the values it doesn't know as they are
+type knownEnum interface {
+	~string
+	IsKnown() bool
+}
+
+// unknownEnumWarning warns about a value aidbox responded with that the provider doesn't know, e.g. because it was
+// set by another client or added in a later version of aidbox. The value is kept in the state as it is, so that it
+// shows up in the plan if the configuration differs.
+func unknownEnumWarning[T knownEnum](path cty.Path, value T) diag.Diagnostics {
+	if value == "" || value.IsKnown() {
+		return nil
+	}
+	return diag.Diagnostics{{
+		Severity:      diag.Warning,
+		Summary:       fmt.Sprintf("Unknown value %q", string(value)),
+		Detail:        fmt.Sprintf("aidbox responded with %q, which this version of the provider doesn't know", string(value)),
+		AttributePath: path,
+	}}
+}
+
+// logImportDiagnostics logs the warnings of mapping an imported resource to its state, as an importer can't return
+// them, and returns the errors among them as one error.
+func logImportDiagnostics(ctx context.Context, diags diag.Diagnostics) error {
+	for _, d := range diags {
+		if d.Severity == diag.Error {
+			continue
+		}
+		tflog.Warn(ctx, d.Summary, map[string]interface{}{
+			"detail":    d.Detail,
+			"attribute": attributePathString(d.AttributePath),
+		})
+	}
+	return errorFromDiagnostics(diags)
+}
+
+// errorFromDiagnostics joins the errors among the diagnostics, for the callers that return an error
+func errorFromDiagnostics(diags diag.Diagnostics) error {
+	var errs []error
+	for _, d := range diags {
+		if d.Severity != diag.Error {
+			continue
+		}
+		if d.Detail == "" {
+			errs = append(errs, errors.New(d.Summary))
+		} else {
+			errs = append(errs, fmt.Errorf("%s: %s", d.Summary, d.Detail))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// attributePathString formats an attribute path the way terraform shows it, e.g. rule[0].engine
+func attributePathString(path cty.Path) string {
+	var b strings.Builder
+	for _, step := range path {
+		switch s := step.(type) {
+		case cty.GetAttrStep:
+			if b.Len() > 0 {
+				b.WriteString(".")
+			}
+			b.WriteString(s.Name)
+		case cty.IndexStep:
+			if s.Key.Type() == cty.Number {
+				fmt.Fprintf(&b, "[%s]", s.Key.AsBigFloat().Text('f', -1))
+			} else if s.Key.Type() == cty.String {
+				fmt.Fprintf(&b, "[%q]", s.Key.AsString())
+			}
+		}
+	}
+	return b.String()
+}
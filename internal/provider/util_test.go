@@ -0,0 +1,174 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-cty/cty"
+	"github.com/hashicorp/terraform-plugin-log/tflogtest"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/diag"
+	"github.com/hashicorp/terraform-plugin-sdk/v2/terraform"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagFromErr(t *testing.T) {
+	t.Run("should report each issue with the attribute it's about", func(t *testing.T) {
+		err := &aidbox.APIError{
+			StatusCode: 422,
+			Status:     "422 Unprocessable Entity",
+			Method:     "PUT",
+			URL:        "http://localhost:8888/AccessPolicy/ap",
+			Issues: []aidbox.OperationOutcomeIssue{
+				{Severity: "fatal", Code: "invalid", Expression: []string{"AccessPolicy.roleName"}, Diagnostics: "expected string"},
+				{Severity: "warning", Code: "informational", Expression: []string{".link[0].resourceType"}},
+				{Severity: "error", Code: "invalid", Expression: []string{"AccessPolicy.unknownKey"}, Diagnostics: "unknown key"},
+			},
+		}
+
+		diags := diagFromErr(err, resourceSchemaAccessPolicy())
+
+		assert.Equal(t, diag.Diagnostics{
+			{
+				Severity:      diag.Error,
+				Summary:       "expected string",
+				Detail:        "PUT http://localhost:8888/AccessPolicy/ap responded with 422 Unprocessable Entity\n\nat AccessPolicy.roleName",
+				AttributePath: cty.GetAttrPath("role_name"),
+			},
+			{
+				Severity:      diag.Warning,
+				Summary:       "informational",
+				Detail:        "PUT http://localhost:8888/AccessPolicy/ap responded with 422 Unprocessable Entity\n\nat .link[0].resourceType",
+				AttributePath: cty.GetAttrPath("link"),
+			},
+			{
+				Severity: diag.Error,
+				Summary:  "unknown key",
+				Detail:   "PUT http://localhost:8888/AccessPolicy/ap responded with 422 Unprocessable Entity\n\nat AccessPolicy.unknownKey",
+			},
+		}, diags)
+	})
+
+	t.Run("should only point at attributes of the schema", func(t *testing.T) {
+		err := &aidbox.APIError{
+			StatusCode: 422,
+			Status:     "422 Unprocessable Entity",
+			Method:     "PUT",
+			URL:        "http://localhost:8888/Patient/pt-1",
+			Issues:     []aidbox.OperationOutcomeIssue{{Severity: "error", Code: "invalid", Expression: []string{"Patient.name"}}},
+		}
+
+		diags := diagFromErr(err, nil)
+
+		assert.Len(t, diags, 1)
+		assert.Nil(t, diags[0].AttributePath)
+		assert.Contains(t, diags[0].Detail, "at Patient.name")
+	})
+
+	t.Run("should still fail if no issue is an error", func(t *testing.T) {
+		err := &aidbox.APIError{
+			StatusCode: 409,
+			Issues:     []aidbox.OperationOutcomeIssue{{Severity: "information", Code: "conflict"}},
+		}
+
+		diags := diagFromErr(err, nil)
+
+		assert.True(t, diags.HasError())
+		assert.Len(t, diags, 2)
+	})
+
+	t.Run("should report other errors as they are", func(t *testing.T) {
+		assert.Equal(t, diag.FromErr(errors.New("Rincewind")), diagFromErr(errors.New("Rincewind"), nil))
+		assert.Equal(t, diag.FromErr(&aidbox.APIError{StatusCode: 500}), diagFromErr(&aidbox.APIError{StatusCode: 500}, nil))
+	})
+}
+
+func TestYamlToJson(t *testing.T) {
+	t.Run("should convert the way the same JSON would be parsed", func(t *testing.T) {
+		converted, err := yamlToJson(`
+# comments are dropped
+resourceType: Patient
+birthDate: 2020-01-01
+active: true
+multipleBirthInteger: 2
+name:
+  - given: [Ann]
+    family: "Smith"
+deceasedBoolean: null
+`)
+
+		assert.Equal(t, nil, err)
+		assert.JSONEq(t, `{"resourceType": "Patient", "birthDate": "2020-01-01", "active": true, "multipleBirthInteger": 2,
+			"name": [{"given": ["Ann"], "family": "Smith"}], "deceasedBoolean": null}`, converted)
+	})
+
+	t.Run("should only accept a mapping", func(t *testing.T) {
+		_, err := yamlToJson("- a\n- b\n")
+		assert.ErrorContains(t, err, "expected a YAML mapping")
+
+		_, err = yamlToJson("")
+		assert.ErrorContains(t, err, "expected a YAML mapping")
+
+		_, err = yamlToJson("a: [")
+		assert.Error(t, err)
+	})
+}
+
+func TestJsonToYaml(t *testing.T) {
+	converted, err := jsonToYaml(`{"resourceType": "Patient", "birthDate": "2020-01-01", "name": [{"given": ["Ann"]}], "active": true}`)
+
+	assert.Equal(t, nil, err)
+	assert.Equal(t, `active: true
+birthDate: "2020-01-01"
+name:
+  - given:
+      - Ann
+resourceType: Patient
+`, converted)
+}
+
+func TestUnknownEnumWarning(t *testing.T) {
+	assert.Empty(t, unknownEnumWarning(cty.GetAttrPath("engine"), aidbox.AccessPolicyEngineAllow))
+	assert.Empty(t, unknownEnumWarning(cty.GetAttrPath("engine"), aidbox.AccessPolicyEngine("")))
+
+	diags := unknownEnumWarning(cty.GetAttrPath("engine"), aidbox.AccessPolicyEngine("prolog"))
+
+	assert.Len(t, diags, 1)
+	assert.Equal(t, diag.Warning, diags[0].Severity)
+	assert.Equal(t, `Unknown value "prolog"`, diags[0].Summary)
+	assert.Equal(t, cty.GetAttrPath("engine"), diags[0].AttributePath)
+}
+
+func TestLogImportDiagnostics(t *testing.T) {
+	var output bytes.Buffer
+	ctx := tflogtest.RootLogger(context.Background(), &output)
+	path := cty.GetAttrPath("rule").IndexInt(0).GetAttr("engine")
+
+	assert.NoError(t, logImportDiagnostics(ctx, unknownEnumWarning(path, aidbox.AccessPolicyEngine("prolog"))))
+
+	entries, err := tflogtest.MultilineJSONDecode(&output)
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+	assert.Equal(t, "warn", entries[0]["@level"])
+	assert.Equal(t, `Unknown value "prolog"`, entries[0]["@message"])
+	assert.Equal(t, "rule[0].engine", entries[0]["attribute"])
+
+	err = logImportDiagnostics(ctx, diag.Errorf("no such engine"))
+	assert.EqualError(t, err, "no such engine")
+}
+
+func TestOptionalBool(t *testing.T) {
+	d := resourceSearchParameterV2().Data(&terraform.InstanceState{
+		RawConfig: cty.ObjectVal(map[string]cty.Value{
+			"multiple_or":  cty.False,
+			"multiple_and": cty.NullVal(cty.Bool),
+		}),
+	})
+
+	assert.Equal(t, false, *optionalBool(d, "multiple_or"))
+	assert.Nil(t, optionalBool(d, "multiple_and"))
+	// without a configuration, e.g. when importing
+	assert.Nil(t, optionalBool(resourceSearchParameterV2().Data(nil), "multiple_or"))
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"flag"
 	"log"
+	"os"
+	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/v2/plugin"
+	"github.com/patientsknowbest/terraform-provider-aidbox/aidbox"
 	"github.com/patientsknowbest/terraform-provider-aidbox/internal/provider"
 )
 
@@ -29,6 +32,11 @@ var (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		generate(os.Args[2:])
+		return
+	}
+
 	var debugMode bool
 
 	flag.BoolVar(&debugMode, "debug", false, "set to true to run the provider with support for debuggers like delve")
@@ -46,3 +54,36 @@ func main() {
 
 	plugin.Serve(opts)
 }
+
+// generate writes terraform configuration and import blocks for the resources already on an aidbox server
+func generate(args []string) {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	types := flags.String("types", "AccessPolicy,Client,SearchParameter", "comma separated aidbox resource types to generate configuration for")
+	out := flags.String("out", ".", "directory to write the generated configuration into")
+	url := flags.String("url", os.Getenv("AIDBOX_URL"), "the aidbox URL, defaults to AIDBOX_URL")
+	clientId := flags.String("client-id", envOrDefault("AIDBOX_CLIENT_ID", "root"), "the client ID, defaults to AIDBOX_CLIENT_ID")
+	clientSecret := flags.String("client-secret", envOrDefault("AIDBOX_CLIENT_SECRET", "secret"), "the client secret, defaults to AIDBOX_CLIENT_SECRET")
+	authMethod := flags.String("auth-method", envOrDefault("AIDBOX_AUTH_METHOD", string(aidbox.AuthMethodBasic)), "basic or client_credentials, defaults to AIDBOX_AUTH_METHOD")
+	flags.Parse(args)
+
+	if *url == "" {
+		log.Fatal("the aidbox URL is required, set it with --url or AIDBOX_URL")
+	}
+	method, err := aidbox.ParseAuthMethod(*authMethod)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+	apiClient := aidbox.NewApiClient(*url, *clientId, *clientSecret)
+	apiClient.AuthMethod = method
+	err = provider.Generate(context.Background(), apiClient, strings.Split(*types, ","), *out)
+	if err != nil {
+		log.Fatal(err.Error())
+	}
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return defaultValue
+}